@@ -1,29 +1,66 @@
 package ast
 
+// Node is implemented by every element of the syntax tree: Chunk, all Stmt,
+// Expr and Type types, *Field, *ParList, *FuncName and *TableTypeField.
+type Node interface {
+	astNode()
+}
+
+// PositionHolder is implemented by every node that covers a range of the
+// source, which is every node except Chunk. Pos is the position of the
+// first character of the node and End the position immediately after it.
 type PositionHolder interface {
 	Line() int
 	SetLine(int)
 	LastLine() int
 	SetLastLine(int)
+	Pos() Position
+	SetPos(Position)
+	End() Position
+	SetEnd(Position)
+}
+
+// NodeBase holds the position of a node, it is embedded by ExprBase,
+// StmtBase and the other nodes with a position.
+//
+// It used to be called Node, which is now the interface of every node of
+// the tree, so it cannot be kept as an alias. Types embedding ast.Node
+// outside of this package must embed ast.NodeBase instead.
+type NodeBase struct {
+	pos Position
+	end Position
+}
+
+func (n *NodeBase) astNode() {}
+
+func (n *NodeBase) Line() int {
+	return n.pos.Line
+}
+
+func (n *NodeBase) SetLine(line int) {
+	n.pos.Line = line
 }
 
-type Node struct {
-	line     int
-	lastline int
+func (n *NodeBase) LastLine() int {
+	return n.end.Line
 }
 
-func (n *Node) Line() int {
-	return n.line
+func (n *NodeBase) SetLastLine(line int) {
+	n.end.Line = line
 }
 
-func (n *Node) SetLine(line int) {
-	n.line = line
+func (n *NodeBase) Pos() Position {
+	return n.pos
 }
 
-func (n *Node) LastLine() int {
-	return n.lastline
+func (n *NodeBase) SetPos(pos Position) {
+	n.pos = pos
 }
 
-func (n *Node) SetLastLine(line int) {
-	n.lastline = line
-}
\ No newline at end of file
+func (n *NodeBase) End() Position {
+	return n.end
+}
+
+func (n *NodeBase) SetEnd(pos Position) {
+	n.end = pos
+}
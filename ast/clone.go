@@ -0,0 +1,74 @@
+package ast
+
+import "reflect"
+
+// Clone returns a deep copy of node. Every Stmt, Expr, Chunk, *Field,
+// *ParList and *FuncName reachable from node is duplicated, including its
+// line information, so the copy can be modified without affecting the
+// original tree.
+func Clone(node Node) Node {
+	if node == nil {
+		return nil
+	}
+	return clone(reflect.ValueOf(node)).Interface().(Node)
+}
+
+func clone(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type().Elem())
+		c.Elem().Set(v.Elem())
+		cloneFields(c.Elem())
+		return c
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(clone(v.Elem()))
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(clone(v.Index(i)))
+		}
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		cloneFields(c)
+		return c
+	}
+	return v
+}
+
+var stmtBaseType = reflect.TypeOf(StmtBase{})
+
+// cloneFields replaces every exported field of the addressable struct v by
+// a deep copy. Unexported fields only hold plain values and are already
+// copied along with the struct, except for statement comments.
+func cloneFields(v reflect.Value) {
+	if v.Kind() != reflect.Struct {
+		return
+	}
+	for i := 0; i < v.NumField(); i++ {
+		if f := v.Field(i); f.CanSet() {
+			f.Set(clone(f))
+		}
+	}
+	if v.Type() == stmtBaseType {
+		s := v.Addr().Interface().(*StmtBase)
+		s.leading = cloneComments(s.leading)
+		s.trailing = cloneComments(s.trailing)
+	}
+}
+
+func cloneComments(g *CommentGroup) *CommentGroup {
+	return clone(reflect.ValueOf(g)).Interface().(*CommentGroup)
+}
@@ -0,0 +1,38 @@
+package ast
+
+import "strings"
+
+// Comment is a single line (--) or long (--[[ ]]) comment. Text holds the
+// comment exactly as written in the source, including the leading dashes.
+type Comment struct {
+	NodeBase
+
+	Text string
+}
+
+// CommentGroup is a sequence of comments with no other tokens in between.
+type CommentGroup struct {
+	List []*Comment
+}
+
+// Text returns the text of all comments in the group, one per line.
+func (g *CommentGroup) Text() string {
+	if g == nil {
+		return ""
+	}
+	lines := make([]string, len(g.List))
+	for i, c := range g.List {
+		lines[i] = c.Text
+	}
+	return strings.Join(lines, "\n")
+}
+
+// CommentHolder is implemented by every statement. Leading comments are the
+// ones found on the lines before the statement, trailing comments follow it
+// on its last line.
+type CommentHolder interface {
+	LeadingComments() *CommentGroup
+	SetLeadingComments(*CommentGroup)
+	TrailingComments() *CommentGroup
+	SetTrailingComments(*CommentGroup)
+}
@@ -0,0 +1,90 @@
+package ast
+
+import "strconv"
+
+// Desugar returns an equivalent statement made of plain assignments.
+//
+// x += 1 becomes x = x + 1. When the target indexes a table, its object and
+// key are evaluated once: a.b[f()] += 1 becomes
+//
+//	do
+//		local __object, __key = a.b, f();
+//		__object[__key] = __object[__key] + 1;
+//	end
+//
+// Identifiers and constants are used as they are instead of being stored in
+// a local, but for an identifier object with a key that is not, as the key
+// may assign to it: a[f()] += 1 stores a too. The locals are renamed, to __object1 and so on, when s already
+// uses their names. The statement returned shares Lhs and Rhs with s.
+func (s *CompoundAssignStmt) Desugar() Stmt {
+	target, ok := s.Lhs.(*AttrGetExpr)
+	if !ok || isSimple(target.Object) && isSimple(target.Key) {
+		return s.assign(s.Lhs, Clone(s.Lhs).(Expr))
+	}
+
+	used := map[string]bool{}
+	Inspect(s, func(n Node) bool {
+		if ident, ok := n.(*IdentExpr); ok {
+			used[ident.Value] = true
+		}
+		return true
+	})
+
+	local := &LocalAssignStmt{}
+	local.SetPos(s.Pos())
+	local.SetEnd(s.Lhs.End())
+	temp := func(expr Expr, base string, keep bool) Expr {
+		if keep {
+			return expr
+		}
+		name := base
+		for i := 1; used[name]; i++ {
+			name = base + strconv.Itoa(i)
+		}
+		local.Names = append(local.Names, name)
+		local.Exprs = append(local.Exprs, expr)
+		ident := &IdentExpr{Value: name}
+		ident.SetPos(expr.Pos())
+		ident.SetEnd(expr.End())
+		return ident
+	}
+	_, ident := target.Object.(*IdentExpr)
+	object := temp(target.Object, "__object", isSimple(target.Object) && !(ident && !isSimple(target.Key)))
+	key := temp(target.Key, "__key", isSimple(target.Key))
+	lhs := &AttrGetExpr{Object: object, Key: key}
+	lhs.SetPos(target.Pos())
+	lhs.SetEnd(target.End())
+
+	do := &DoBlockStmt{Chunk: Chunk{local, s.assign(lhs, Clone(lhs).(Expr))}}
+	do.SetPos(s.Pos())
+	do.SetEnd(s.End())
+	return do
+}
+
+// assign returns the assignment lhs = value op s.Rhs.
+func (s *CompoundAssignStmt) assign(lhs, value Expr) *AssignStmt {
+	var rhs Expr
+	switch s.Operator {
+	case OpConcat:
+		rhs = &StringConcatOpExpr{Lhs: value, Rhs: s.Rhs}
+	default:
+		rhs = &ArithmeticOpExpr{Lhs: value, Operator: s.Operator, Rhs: s.Rhs}
+	}
+	rhs.SetPos(value.Pos())
+	rhs.SetEnd(s.Rhs.End())
+
+	assign := &AssignStmt{Lhs: []Expr{lhs}, Rhs: []Expr{rhs}}
+	assign.SetPos(s.Pos())
+	assign.SetEnd(s.End())
+	return assign
+}
+
+// isSimple reports whether evaluating expr twice has no effect and gives the
+// same result, as far as the syntax tells.
+func isSimple(expr Expr) bool {
+	switch expr.(type) {
+	case *IdentExpr, *StringExpr, *NumberExpr, *NilExpr, *TrueExpr, *FalseExpr:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,354 @@
+package ast
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Expressions are wrapped to PrintConfig.MaxWidth by turning them into
+// documents, in the style of Wadler's "A prettier printer". A document is
+// text with optional line breaks, grouped so that a group either fits on
+// the rest of the line with all its breaks flat or has all of them broken.
+
+// tabWidth is the width of a tab when measuring lines.
+const tabWidth = 4
+
+type doc interface{}
+
+// docText is text without newlines.
+type docText string
+
+// docLine is a line break, or a space (nothing when soft) inside a flat
+// group.
+type docLine struct{ soft bool }
+
+// docGroup is printed flat if it fits on the line, broken otherwise.
+type docGroup struct{ doc doc }
+
+// docNest indents the lines broken inside it by one more level.
+type docNest struct{ doc doc }
+
+// docIfBreak is text only printed when the enclosing group is broken.
+type docIfBreak string
+
+// docBlock is text printed by the builder, given the indentation level and
+// the column it starts at. It may span lines, only its first and last ones
+// are measured.
+type docBlock func(level, column int) string
+
+type docConcat []doc
+
+var (
+	line     = docLine{}
+	softline = docLine{soft: true}
+)
+
+// docList is a group of items separated by commas, broken one item per
+// line between open and close.
+func docList(open string, items []doc, trailing bool, close string) doc {
+	list := docConcat{softline}
+	for i, item := range items {
+		if i > 0 {
+			list = append(list, docText(","), line)
+		}
+		list = append(list, item)
+	}
+	if trailing {
+		list = append(list, docIfBreak(","))
+	}
+	return docGroup{docConcat{docText(open), docNest{list}, softline, docText(close)}}
+}
+
+type docItem struct {
+	level int
+	flat  bool
+	doc   doc
+}
+
+// render prints d at the current position, breaking the lines that do not
+// fit in MaxWidth.
+func (s *builder) render(d doc) {
+	column := s.column()
+	stack := []docItem{{s.Indent, false, d}}
+	for len(stack) > 0 {
+		item := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		switch d := item.doc.(type) {
+		case docText:
+			s.add(string(d))
+			column += width(string(d))
+		case docIfBreak:
+			if !item.flat {
+				s.add(string(d))
+				column += width(string(d))
+			}
+		case docLine:
+			switch {
+			case !item.flat:
+				s.addln("")
+				s.add(s.indentation(item.level))
+				column = width(s.indentation(item.level))
+			case !d.soft:
+				s.addrune(' ')
+				column++
+			}
+		case docBlock:
+			str := d(item.level, column)
+			s.add(str)
+			if i := strings.LastIndexByte(str, '\n'); i >= 0 {
+				column = width(str[i+1:])
+			} else {
+				column += width(str)
+			}
+		case docGroup:
+			flat := item.flat || s.fits(s.config.MaxWidth-column, docItem{item.level, true, d.doc}, stack)
+			stack = append(stack, docItem{item.level, flat, d.doc})
+		case docNest:
+			stack = append(stack, docItem{nest(item), item.flat, d.doc})
+		case docConcat:
+			for i := len(d) - 1; i >= 0; i-- {
+				stack = append(stack, docItem{item.level, item.flat, d[i]})
+			}
+		}
+	}
+}
+
+// fits reports whether next, followed by the rest of the stack, fits in the
+// remaining columns until the next line break.
+func (s *builder) fits(remaining int, next docItem, rest []docItem) bool {
+	stack := []docItem{next}
+	for remaining >= 0 {
+		if len(stack) == 0 {
+			if len(rest) == 0 {
+				return true
+			}
+			stack = append(stack, rest[len(rest)-1])
+			rest = rest[:len(rest)-1]
+		}
+		item := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		switch d := item.doc.(type) {
+		case docText:
+			remaining -= width(string(d))
+		case docIfBreak:
+			if !item.flat {
+				remaining -= width(string(d))
+			}
+		case docLine:
+			if !item.flat {
+				return true
+			}
+			if !d.soft {
+				remaining--
+			}
+		case docBlock:
+			str := d(item.level, s.config.MaxWidth-remaining)
+			if i := strings.IndexByte(str, '\n'); i >= 0 {
+				if width(str[:i]) > remaining {
+					return false
+				}
+				// What follows the block has to fit after its last line.
+				remaining = s.config.MaxWidth - width(str[strings.LastIndexByte(str, '\n')+1:])
+				continue
+			}
+			remaining -= width(str)
+		case docGroup:
+			stack = append(stack, docItem{item.level, item.flat, d.doc})
+		case docNest:
+			stack = append(stack, docItem{nest(item), item.flat, d.doc})
+		case docConcat:
+			for i := len(d) - 1; i >= 0; i-- {
+				stack = append(stack, docItem{item.level, item.flat, d[i]})
+			}
+		}
+	}
+	return false
+}
+
+// nest returns the level of the lines broken inside a nest. Flat nests keep
+// the level, for blocks to be indented like the line they start on.
+func nest(item docItem) int {
+	if item.flat {
+		return item.level
+	}
+	return item.level + 1
+}
+
+// width returns how many columns str takes.
+func width(str string) int {
+	if strings.IndexByte(str, markByte) >= 0 {
+		str, _ = unmark(str, nil)
+	}
+	return utf8.RuneCountInString(str) + strings.Count(str, "\t")*(tabWidth-1)
+}
+
+// column returns the column the builder is at.
+func (s *builder) column() int {
+	str := s.Str.String()
+	if i := strings.LastIndexByte(str, '\n'); i >= 0 {
+		return width(str[i+1:])
+	}
+	return s.start + width(str)
+}
+
+// doc returns the document of an expression, printed like expr prints it.
+func (s *builder) doc(ex Expr, d data) doc {
+	ex = s.unparen(ex)
+	switch ex.(type) {
+	case *AttrGetExpr, *TableExpr, *LogicalOpExpr, *RelationalOpExpr, *StringConcatOpExpr, *ArithmeticOpExpr, *UnaryOpExpr, *FuncCallExpr, *ParenExpr:
+		if mark := s.markString(ex); mark != "" {
+			return docConcat{docText(mark), s.docNode(ex, d)}
+		}
+	}
+	return s.docNode(ex, d)
+}
+
+func (s *builder) docNode(ex Expr, d data) doc {
+	switch e := ex.(type) {
+	case *NilExpr, *FalseExpr, *TrueExpr, *IdentExpr, *Comma3Expr, *NumberExpr, *StringExpr:
+		b := s.sub()
+		b.exprNode(ex, d)
+		return docText(b.Str.String())
+	case *ParenExpr:
+		return docConcat{docText("("), s.doc(e.Expr, data{}), docText(")")}
+	case *AttrGetExpr:
+		var object doc = docText("string")
+		if !s.isStringObject(e.Object) {
+			object = s.prefixDoc(e.Object, d)
+		}
+		if str, ok := keyName(e.Key); ok {
+			return docConcat{object, docText("." + s.markString(str) + str.Value)}
+		}
+		open, close := s.index(e.Key)
+		return docConcat{object, docText(open), s.doc(e.Key, d), docText(close)}
+	case *TableExpr:
+		if len(e.Fields) == 0 {
+			return docText("{}")
+		}
+		fields := make([]doc, len(e.Fields))
+		for i, field := range e.Fields {
+			fields[i] = s.fieldDoc(field, d)
+		}
+		return docList("{", fields, s.config.TrailingCommas, "}")
+	case *LogicalOpExpr:
+		return s.binaryDoc(e.Operator, e.Lhs, e.Rhs, d)
+	case *RelationalOpExpr:
+		return s.binaryDoc(e.Operator, e.Lhs, e.Rhs, d)
+	case *StringConcatOpExpr:
+		return s.binaryDoc(OpConcat, e.Lhs, e.Rhs, d)
+	case *ArithmeticOpExpr:
+		return s.binaryDoc(e.Operator, e.Lhs, e.Rhs, d)
+	case *UnaryOpExpr:
+		op := e.Operator.String()
+		if e.Operator == OpNot {
+			op += " "
+		}
+		unary := docConcat{docText(op), s.doc(e.Expr, data{Precedence: e.Operator.Precedence(), Parent: e.Operator})}
+		if wrapsUnary(e.Operator, d) {
+			return docConcat{docText("("), unary, docText(")")}
+		}
+		return unary
+	case *FuncCallExpr:
+		return s.callDoc(e, d)
+	}
+	return s.block(ex, d)
+}
+
+// block returns the document of an expression printed by exprNode.
+func (s *builder) block(ex Expr, d data) doc {
+	printed := map[[2]int]string{}
+	return docBlock(func(level, column int) string {
+		str, ok := printed[[2]int{level, column}]
+		if !ok {
+			b := s.sub()
+			b.Indent, b.start = level, column
+			b.exprNode(ex, d)
+			str = b.Str.String()
+			printed[[2]int{level, column}] = str
+		}
+		return str
+	})
+}
+
+// binaryDoc returns the document of a binary operation. Operands of the same
+// precedence are put in a single group, broken before each operator.
+func (s *builder) binaryDoc(op Op, lhs, rhs Expr, d data) doc {
+	precedence := op.Precedence()
+	wrap := precedence < d.Precedence || (precedence == d.Precedence && op.RightAssoc() != d.Direction)
+	operation := docConcat{
+		s.doc(lhs, data{precedence, false, op}),
+		line,
+		docText(op.String() + " "),
+		s.doc(rhs, data{precedence, true, op}),
+	}
+	if wrap {
+		return docGroup{docConcat{docText("("), docNest{operation}, docText(")")}}
+	}
+	if d.Parent.Precedence() == precedence {
+		return operation
+	}
+	return docGroup{docNest{operation}}
+}
+
+func (s *builder) callDoc(e *FuncCallExpr, d data) doc {
+	var prefix doc
+	if e.Func != nil {
+		prefix = s.prefixDoc(e.Func, d)
+	} else {
+		prefix = docConcat{s.prefixDoc(e.Receiver, data{}), docText(":" + e.Method)}
+	}
+
+	if s.omitsCallParens(e) {
+		return docConcat{prefix, docText(" "), s.doc(e.Args[0], d)}
+	}
+	args := make([]doc, len(e.Args))
+	for i, arg := range e.Args {
+		args[i] = s.doc(arg, d)
+	}
+	return docConcat{prefix, docList("(", args, false, ")")}
+}
+
+// prefixDoc returns the document of the object of an index or the function
+// or the receiver of a call.
+func (s *builder) prefixDoc(ex Expr, d data) doc {
+	if s.isPrefix(ex) {
+		return s.doc(s.prefixExpr(ex), d)
+	}
+	return docConcat{docText("("), s.doc(ex, d), docText(")")}
+}
+
+func (s *builder) fieldDoc(field *Field, d data) doc {
+	if field.Key == nil {
+		return s.doc(field.Value, d)
+	}
+	if str, ok := keyName(field.Key); ok {
+		return docConcat{docText(str.Value + " = "), s.doc(field.Value, d)}
+	}
+	open, close := s.index(field.Key)
+	return docConcat{docText(open), s.doc(field.Key, d), docText(close + " = "), s.doc(field.Value, d)}
+}
+
+// paramsDoc returns the document of the parameter list of a function.
+func (s *builder) paramsDoc(params *ParList) doc {
+	var items []doc
+	param := func(name string, t Type) {
+		b := s.sub()
+		b.add(name)
+		if t != nil {
+			b.add(": ")
+			b.typ(t, 0)
+		}
+		items = append(items, docText(b.Str.String()))
+	}
+	for i, name := range params.Names {
+		var t Type
+		if i < len(params.Types) {
+			t = params.Types[i]
+		}
+		param(name, t)
+	}
+	if params.HasVargs {
+		param("...", params.VarargType)
+	}
+	return docList("(", items, false, ")")
+}
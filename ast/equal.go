@@ -0,0 +1,118 @@
+package ast
+
+import "reflect"
+
+// EqualOptions controls how Equal compares two syntax trees.
+type EqualOptions struct {
+	// IgnorePositions makes Equal disregard the line information of nodes,
+	// so trees built by hand compare equal to parsed ones.
+	IgnorePositions bool
+
+	// IgnoreComments makes Equal disregard comments attached to statements
+	// and CommentStmt nodes.
+	IgnoreComments bool
+}
+
+var (
+	nodeBaseType   = reflect.TypeOf(NodeBase{})
+	numberExprType = reflect.TypeOf(NumberExpr{})
+	stringExprType = reflect.TypeOf(StringExpr{})
+)
+
+// Equal reports whether a and b are structurally identical syntax trees.
+// Nil and empty slices are considered equal, numbers and strings are compared
+// by value whatever their spelling, though an integer never equals a float,
+// and parentheses that do not change the
+// meaning of an expression are ignored. A nil opts is the same as
+// the zero EqualOptions, which compares positions as well.
+func Equal(a, b Node, opts *EqualOptions) bool {
+	if opts == nil {
+		opts = &EqualOptions{}
+	}
+	if opts.IgnoreComments {
+		a, b = stripComments(a), stripComments(b)
+	}
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return equal(unparenValue(reflect.ValueOf(a)), unparenValue(reflect.ValueOf(b)), opts)
+}
+
+// unparenValue strips the redundant parentheses around an expression.
+func unparenValue(v reflect.Value) reflect.Value {
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return v
+	}
+	if ex, ok := v.Interface().(Expr); ok {
+		return reflect.ValueOf(unparen(ex))
+	}
+	return v
+}
+
+// stripComments returns a copy of node without CommentStmt nodes.
+func stripComments(node Node) Node {
+	if node == nil {
+		return nil
+	}
+	return Apply(Clone(node), func(c *Cursor) bool {
+		if _, ok := c.Node().(*CommentStmt); ok && c.Index() >= 0 {
+			c.Delete()
+		}
+		return true
+	}, nil)
+}
+
+func equal(a, b reflect.Value, opts *EqualOptions) bool {
+	if a.Type() != b.Type() {
+		return false
+	}
+	switch a.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		if a.Kind() == reflect.Interface {
+			return equal(unparenValue(a.Elem()), unparenValue(b.Elem()), opts)
+		}
+		return equal(a.Elem(), b.Elem(), opts)
+	case reflect.Slice:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for i := 0; i < a.Len(); i++ {
+			if !equal(a.Index(i), b.Index(i), opts) {
+				return false
+			}
+		}
+		return true
+	case reflect.Struct:
+		if a.Type() == nodeBaseType {
+			return opts.IgnorePositions || a.Interface() == b.Interface()
+		}
+		if a.Type() == numberExprType {
+			na, nb := a.Interface().(NumberExpr), b.Interface().(NumberExpr)
+			return na.Integer == nb.Integer && sameNumber(&na, &nb) && equal(a.FieldByName("ConstExprBase"), b.FieldByName("ConstExprBase"), opts)
+		}
+		if a.Type() == stringExprType {
+			return a.FieldByName("Value").String() == b.FieldByName("Value").String() &&
+				equal(a.FieldByName("ConstExprBase"), b.FieldByName("ConstExprBase"), opts)
+		}
+		if a.Type() == stmtBaseType && !opts.IgnoreComments {
+			sa, sb := a.Interface().(StmtBase), b.Interface().(StmtBase)
+			if !equal(reflect.ValueOf(sa.leading), reflect.ValueOf(sb.leading), opts) ||
+				!equal(reflect.ValueOf(sa.trailing), reflect.ValueOf(sb.trailing), opts) {
+				return false
+			}
+		}
+		for i := 0; i < a.NumField(); i++ {
+			if a.Type().Field(i).PkgPath != "" {
+				continue
+			}
+			if !equal(a.Field(i), b.Field(i), opts) {
+				return false
+			}
+		}
+		return true
+	}
+	return a.Interface() == b.Interface()
+}
@@ -1,13 +1,14 @@
 package ast
 
 type Expr interface {
+	Node
 	PositionHolder
 	exprMarker()
 	String() string
 }
 
 type ExprBase struct {
-	Node
+	NodeBase
 }
 
 func (expr *ExprBase) exprMarker() {}
@@ -37,16 +38,30 @@ type NilExpr struct {
 	ConstExprBase
 }
 
+// NumberExpr is a numeric literal. Like in Lua 5.3, numbers without a
+// fraction or an exponent that fit in an int64 are integers.
 type NumberExpr struct {
 	ConstExprBase
 
-	Value float64
+	Value   float64 // value of the number, converted to a float for integers
+	Integer bool
+	Int     int64 // value of an integer
+	// Raw is the text of the number in the source, like 0xff or 1_000. The
+	// printer writes it when it still spells the number and the canonical
+	// spelling otherwise.
+	Raw string
 }
 
 type StringExpr struct {
 	ConstExprBase
 
 	Value string
+	// Quote is the quote the string had in the source, '"' or '\'', or '['
+	// for a long bracket string of level LongBracketLevel. It is 0 for
+	// strings that were not parsed. The printer only uses them when
+	// PrintConfig.KeepQuotes is set, Equal ignores them.
+	Quote            byte
+	LongBracketLevel int
 }
 
 /* ConstExprs }}} */
@@ -55,6 +70,22 @@ type Comma3Expr struct {
 	ExprBase
 }
 
+// ParenExpr is an expression in parentheses. They are only meaningful
+// around a call or ..., which they truncate to a single value, the printer
+// drops the others unless PrintConfig.KeepParens is set and Equal ignores
+// them.
+type ParenExpr struct {
+	ExprBase
+
+	Expr Expr
+}
+
+// BadExpr is a placeholder for an expression containing syntax errors, it
+// is only produced when parsing with error recovery.
+type BadExpr struct {
+	ExprBase
+}
+
 type IdentExpr struct {
 	ExprBase
 
@@ -87,7 +118,7 @@ type FuncCallExpr struct {
 type LogicalOpExpr struct {
 	ExprBase
 
-	Operator string
+	Operator Op
 	Lhs      Expr
 	Rhs      Expr
 }
@@ -95,7 +126,7 @@ type LogicalOpExpr struct {
 type RelationalOpExpr struct {
 	ExprBase
 
-	Operator string
+	Operator Op
 	Lhs      Expr
 	Rhs      Expr
 }
@@ -110,7 +141,7 @@ type StringConcatOpExpr struct {
 type ArithmeticOpExpr struct {
 	ExprBase
 
-	Operator string
+	Operator Op
 	Lhs      Expr
 	Rhs      Expr
 }
@@ -118,13 +149,45 @@ type ArithmeticOpExpr struct {
 type UnaryOpExpr struct {
 	ExprBase
 
-	Operator string
-	Expr Expr
+	Operator Op
+	Expr     Expr
 }
 
+// FunctionExpr is a function body. It always starts at the function
+// keyword, so for function statements it also covers the name.
 type FunctionExpr struct {
 	ExprBase
 
-	ParList *ParList
-	Chunk   Chunk
+	Generics    []string // Luau generic names, packs end with "..."
+	ParList     *ParList
+	ReturnTypes []Type // Luau return type annotation, nil if none
+	Chunk       Chunk
+}
+
+// InterpolatedStringExpr is a Luau interpolated string like `a {b} c`.
+// Segments holds the literal text around the expressions, so it has one
+// more element than Exprs.
+type InterpolatedStringExpr struct {
+	ExprBase
+
+	Segments []string
+	Exprs    []Expr
+}
+
+// IfExpr is a Luau if-then-else expression. Every elseif is an IfExpr in
+// the Else of the previous one.
+type IfExpr struct {
+	ExprBase
+
+	Condition Expr
+	Then      Expr
+	Else      Expr
+}
+
+// TypeCastExpr is a Luau type assertion like expr :: Type.
+type TypeCastExpr struct {
+	ExprBase
+
+	Expr Expr
+	Type Type
 }
\ No newline at end of file
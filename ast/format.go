@@ -2,7 +2,6 @@ package ast
 
 import (
 	"fmt"
-	"strconv"
 	"strings"
 
 	"github.com/notnoobmaster/luautil"
@@ -11,32 +10,259 @@ import (
 type data struct {
 	Precedence int
 	Direction  bool // true: right, false: left
-	Parent     string
+	Parent     Op
 }
 
 type builder struct {
 	Str    *strings.Builder
 	Indent int
+	config PrintConfig
+	start  int // column Str starts at
+
+	// Compact printing state: whether whitespace was skipped, the last byte
+	// written and whether it ended a number.
+	space  bool
+	last   byte
+	number bool
+
+	marks  *[]Position // positions of the nodes marked for the source map
+	marked string      // marks waiting for the next byte in compact mode
+
+	prefix string // written before the indentation of each line
 }
 
 // Helper functions
-func (s *builder) add(str string)      { s.Str.WriteString(str) }
-func (s *builder) addln(str string)    { s.Str.WriteString(str + "\n") }
-func (s *builder) addrune(r rune)      { s.Str.WriteRune(r) }
-func (s *builder) addpad(str string)   { s.Str.WriteString(" " + str + " ") }
-func (s *builder) tab() *builder       { s.Str.WriteString(strings.Repeat("\t", s.Indent)); return s }
+func (s *builder) addln(str string)    { s.add(str + "\n") }
+func (s *builder) addpad(str string)   { s.add(" " + str + " ") }
+func (s *builder) sub() *builder       { return &builder{Str: &strings.Builder{}, Indent: s.Indent, config: s.config, marks: s.marks, prefix: s.prefix} }
 func (s *builder) wrap(e Expr, d data) { s.add("("); s.expr(e, d); s.add(")") }
 
+func (s *builder) add(str string) {
+	if s.config.Compact {
+		s.compact(str)
+		return
+	}
+	s.Str.WriteString(str)
+}
+
+func (s *builder) addrune(r rune) {
+	if s.config.Compact {
+		s.compact(string(r))
+		return
+	}
+	s.Str.WriteRune(r)
+}
+
+func (s *builder) tab() *builder { s.add(s.indentation(s.Indent)); return s }
+
+func (s *builder) indentation(level int) string {
+	if s.config.Indent > 0 {
+		return s.prefix + strings.Repeat(" ", level*s.config.Indent)
+	}
+	return s.prefix + strings.Repeat("\t", level)
+}
+
+// quote returns str as a quoted string literal.
+func (s *builder) quote(str string) string {
+	quote := s.config.Quote
+	switch quote {
+	case 0:
+		return luautil.Quote(str)
+	case '"':
+		if strings.Count(str, `"`) > strings.Count(str, "'") {
+			quote = '\''
+		}
+	default:
+		if strings.Count(str, "'") > strings.Count(str, `"`) {
+			quote = '"'
+		}
+	}
+	return luautil.QuoteWith(str, quote)
+}
+
+// stringLiteral returns the literal of a string, written like in the
+// source when KeepQuotes is set and the source style is known.
+func (s *builder) stringLiteral(e *StringExpr) string {
+	if s.config.KeepQuotes {
+		switch e.Quote {
+		case '"', '\'':
+			return luautil.QuoteWith(e.Value, e.Quote)
+		case '[':
+			if str, ok := longString(e.Value, e.LongBracketLevel); ok {
+				return s.escapeMarks(str)
+			}
+		}
+	}
+	return s.quote(e.Value)
+}
+
+// longString returns str in long brackets of at least the given level, false
+// if it holds a carriage return which would be read as a newline.
+func longString(str string, level int) (string, bool) {
+	if strings.IndexByte(str, '\r') >= 0 {
+		return "", false
+	}
+	for {
+		close := "]" + strings.Repeat("=", level) + "]"
+		if strings.Index(str+close, close) == len(str) {
+			open := "[" + strings.Repeat("=", level) + "["
+			if strings.HasPrefix(str, "\n") {
+				// The newline right after the bracket is skipped.
+				open += "\n"
+			}
+			return open + str + close, true
+		}
+		level++
+	}
+}
+
+// index returns the brackets around an index or a table key. They are
+// spaced out around a key starting with a long bracket, which would make a
+// longer one with the [ before it.
+func (s *builder) index(key Expr) (open, close string) {
+	if s.startsWithBracket(key) {
+		return "[ ", " ]"
+	}
+	return "[", "]"
+}
+
+// startsWithBracket reports whether ex may be printed starting with a long
+// bracket string.
+func (s *builder) startsWithBracket(ex Expr) bool {
+	switch e := s.unparen(ex).(type) {
+	case *StringExpr:
+		return strings.HasPrefix(s.stringLiteral(e), "[")
+	case *LogicalOpExpr:
+		return s.startsWithBracket(e.Lhs)
+	case *RelationalOpExpr:
+		return s.startsWithBracket(e.Lhs)
+	case *StringConcatOpExpr:
+		return s.startsWithBracket(e.Lhs)
+	case *ArithmeticOpExpr:
+		return s.startsWithBracket(e.Lhs)
+	case *TypeCastExpr:
+		return s.startsWithBracket(e.Expr)
+	}
+	return false
+}
+
+// keyName returns the key of an index or a table field when it is written
+// as a name, ok is false when it must be written in brackets.
+func keyName(key Expr) (str *StringExpr, ok bool) {
+	str, ok = key.(*StringExpr)
+	return str, ok && isValid(str.Value) && !isReserved(str.Value)
+}
+
+// isPrefix reports whether ex is written without parentheses as the object
+// of an index or the function of a call.
+func (s *builder) isPrefix(ex Expr) bool {
+	switch s.unparen(ex).(type) {
+	case *IdentExpr, *AttrGetExpr, *FuncCallExpr, *ParenExpr:
+		return true
+	}
+	return false
+}
+
+// prefixExpr returns the object of an index or the function or the receiver
+// of a call as it is printed. Parentheses around a call are dropped there, it
+// only gives one value in a prefix anyway.
+func (s *builder) prefixExpr(ex Expr) Expr {
+	ex = s.unparen(ex)
+	if paren, ok := ex.(*ParenExpr); ok && !s.config.KeepParens {
+		if _, ok := paren.Expr.(*FuncCallExpr); ok {
+			return paren.Expr
+		}
+	}
+	return ex
+}
+
+// isStringObject reports whether ex, the object of an index, is the empty
+// string. It is written as string, the table of its methods.
+func (s *builder) isStringObject(ex Expr) bool {
+	str, ok := s.unparen(ex).(*StringExpr)
+	return ok && str.Value == ""
+}
+
+// omitsCallParens reports whether the argument of call is written without
+// parentheses, OmitCallParens allows it for a single string or table.
+func (s *builder) omitsCallParens(call *FuncCallExpr) bool {
+	if !s.config.OmitCallParens || len(call.Args) != 1 {
+		return false
+	}
+	switch s.unparen(call.Args[0]).(type) {
+	case *StringExpr, *TableExpr:
+		return true
+	}
+	return false
+}
+
+// unparen returns ex without the parentheses around it, unless KeepParens
+// is set or they truncate a call or ... to a single value.
+func (s *builder) unparen(ex Expr) Expr {
+	if s.config.KeepParens {
+		return ex
+	}
+	return unparen(ex)
+}
+
+// unparen strips the parentheses around ex that do not change its meaning,
+// it keeps the ones truncating a call or ... to a single value.
+func unparen(ex Expr) Expr {
+	for {
+		paren, ok := ex.(*ParenExpr)
+		if !ok {
+			return ex
+		}
+		switch paren.Expr.(type) {
+		case *FuncCallExpr, *Comma3Expr:
+			return ex
+		}
+		ex = paren.Expr
+	}
+}
+
+func (s *builder) comments(g *CommentGroup) {
+	if g == nil || s.config.Compact {
+		return
+	}
+	for _, c := range g.List {
+		s.tab().addln(s.escapeMarks(c.Text))
+	}
+}
+
 func (s *builder) addcomma(idx int, length int) {
 	if idx < length-1 {
-		s.Str.WriteString(", ")
+		s.add(", ")
 	}
 }
 
+// expr prints an expression, wrapped to MaxWidth when it is set.
 func (s *builder) expr(ex Expr, d data) {
+	if s.config.MaxWidth > 0 {
+		s.render(s.doc(ex, d))
+		return
+	}
+	s.exprNode(ex, d)
+}
+
+func (s *builder) exprNode(ex Expr, d data) {
+	ex = s.unparen(ex)
+	s.mark(ex)
 	switch e := ex.(type) {
 	case *NumberExpr:
-		s.add(strconv.FormatFloat(e.Value, 'f', -1, 64))
+		str := e.String()
+		if s.config.NormalizeNumbers {
+			str = e.Canonical()
+		}
+		// A negative float reads like a unary minus, so it is wrapped
+		// like one.
+		if strings.HasPrefix(str, "-") && wrapsUnary(OpNeg, d) {
+			s.add("(")
+			s.literal(str, true)
+			s.add(")")
+		} else {
+			s.literal(str, true)
+		}
 	case *NilExpr:
 		s.add("nil")
 	case *FalseExpr:
@@ -47,146 +273,205 @@ func (s *builder) expr(ex Expr, d data) {
 		s.add(e.Value)
 	case *Comma3Expr:
 		s.add("...")
+	case *BadExpr:
+		s.add("--[[bad expression]]")
 	case *StringExpr:
-		s.add(luautil.Quote(e.Value))
+		s.literal(s.stringLiteral(e), false)
+	case *ParenExpr:
+		s.add("(")
+		s.expr(e.Expr, data{})
+		s.add(")")
 	case *AttrGetExpr:
-		switch obj := e.Object.(type) {
-		case *IdentExpr, *AttrGetExpr:
-			s.expr(e.Object, d)
-		case *StringExpr:
-			if obj.Value == "" {
-				s.add("string")
-				break
-			}
-			s.wrap(e.Object, d)
+		switch {
+		case s.isPrefix(e.Object):
+			s.expr(s.prefixExpr(e.Object), d)
+		case s.isStringObject(e.Object):
+			s.add("string")
 		default:
 			s.wrap(e.Object, d)
 		}
 
-		if str, ok := e.Key.(*StringExpr); ok && isValid(str.Value) && !isReserved(str.Value) {
+		if str, ok := keyName(e.Key); ok {
 			s.add(".")
+			s.mark(str)
 			s.add(str.Value)
 		} else {
-			s.add("[")
+			open, close := s.index(e.Key)
+			s.add(open)
 			s.expr(e.Key, d)
-			s.add("]")
+			s.add(close)
 		}
 	case *TableExpr:
-		s.add("{")
-		s.Indent++
-		length := len(e.Fields)
-		for idx, field := range e.Fields {
-			s.addln("")
-			s.tab()
-			if field.Key != nil {
-				if str, ok := field.Key.(*StringExpr); ok && isValid(str.Value) && !isReserved(str.Value){
-					s.add(str.Value)
-				} else {
-					s.add("[")
-					s.expr(field.Key, d)
-					s.add("]")
-				}
-				s.add(" = ")
-			}
-			s.expr(field.Value, d)
-			if idx < length-1 {
-				s.Str.WriteRune(',')
-				continue
-			}
-			s.addln("")
-			s.Indent--
-			s.tab()
-			s.Indent++
-		}
-		s.Indent--
-		s.add("}")
+		s.table(e, d)
 	case *LogicalOpExpr:
-		if e.Operator == "or" {
-			s.wrapIfNeeded(1, false, "or", e.Lhs, e.Rhs, d)
-		} else {
-			s.wrapIfNeeded(2, false, "and", e.Lhs, e.Rhs, d)
-		}
+		s.wrapIfNeeded(e.Operator, e.Lhs, e.Rhs, d)
 	case *RelationalOpExpr:
-		s.wrapIfNeeded(3, false, e.Operator, e.Lhs, e.Rhs, d)
+		s.wrapIfNeeded(e.Operator, e.Lhs, e.Rhs, d)
 	case *StringConcatOpExpr:
-		s.wrapIfNeeded(8, true, "..", e.Lhs, e.Rhs, d)
+		s.wrapIfNeeded(OpConcat, e.Lhs, e.Rhs, d)
 	case *ArithmeticOpExpr:
-		switch e.Operator {
-		case "|":
-			s.wrapIfNeeded(4, false, e.Operator, e.Lhs, e.Rhs, d)
-		case "~":
-			s.wrapIfNeeded(5, false, e.Operator, e.Lhs, e.Rhs, d)
-		case "&":
-			s.wrapIfNeeded(6, false, e.Operator, e.Lhs, e.Rhs, d)
-		case "<<", ">>":
-			s.wrapIfNeeded(7, false, e.Operator, e.Lhs, e.Rhs, d)
-		case "+", "-":
-			s.wrapIfNeeded(9, false, e.Operator, e.Lhs, e.Rhs, d)
-		case "*", "/", "//", "%":
-			s.wrapIfNeeded(10, false, e.Operator, e.Lhs, e.Rhs, d)
-		case "^":
-			s.wrapIfNeeded(12, true, e.Operator, e.Lhs, e.Rhs, d)
-		default:
-			panic("Unimplemented arithmetic operator: " + e.Operator)
-		}
+		s.wrapIfNeeded(e.Operator, e.Lhs, e.Rhs, d)
 	case *UnaryOpExpr:
-		if 8 < d.Precedence || d.Direction {
+		wrap := wrapsUnary(e.Operator, d)
+		if wrap {
 			s.add("(")
-			s.add(e.Operator)
-			s.expr(e.Expr, data{Precedence: 11})
+		}
+		s.add(e.Operator.String())
+		if e.Operator == OpNot {
+			s.add(" ")
+		}
+		s.expr(e.Expr, data{Precedence: e.Operator.Precedence(), Parent: e.Operator})
+		if wrap {
 			s.add(")")
-		} else {
-			s.add(e.Operator)
-			s.expr(e.Expr, data{Precedence: 11})
 		}
 	case *FuncCallExpr:
-		if e.Func != nil { // hoge.func()
-			switch e.Func.(type) {
-			case *IdentExpr, *AttrGetExpr:
-				s.expr(e.Func, d)
-			default:
-				s.wrap(e.Func, d)
+		s.call(e, d)
+	case *FunctionExpr:
+		s.add("function")
+		s.funcBody(e)
+	case *InterpolatedStringExpr:
+		str := &strings.Builder{}
+		str.WriteString("`")
+		for i, segment := range e.Segments {
+			str.WriteString(luautil.EscapeInterpolated(segment))
+			if i == len(e.Exprs) {
+				break
 			}
-		} else { // hoge:method()
-			switch e.Receiver.(type) {
-			case *IdentExpr, *AttrGetExpr:
-				s.expr(e.Receiver, data{})
-			default:
-				s.wrap(e.Receiver, data{})
+			expr := s.sub()
+			expr.config.MaxWidth = 0 // the braces hold a single line
+			expr.expr(e.Exprs[i], data{})
+			// {{ is not allowed, so a table starting the expression is
+			// separated from the braces.
+			if code, _ := unmark(expr.Str.String(), nil); strings.HasPrefix(code, "{") {
+				str.WriteString("{ " + expr.Str.String() + " }")
+			} else {
+				str.WriteString("{" + expr.Str.String() + "}")
 			}
-			s.add(":")
-			s.add(e.Method)
 		}
-
-		s.add("(")
-		for i := range e.Args {
-			s.expr(e.Args[i], d)
-			s.addcomma(i, len(e.Args))
-		}
-		s.add(")")
-	case *FunctionExpr:
-		s.add("function(")
-		for i, name := range e.ParList.Names {
-			s.add(name)
-			s.addcomma(i, len(e.ParList.Names))
+		str.WriteString("`")
+		s.literal(str.String(), false)
+	case *IfExpr:
+		// The else branch extends as far right as possible, so an if
+		// expression used as an operand needs parentheses.
+		if d.Precedence > 0 {
+			s.add("(")
+			defer s.add(")")
 		}
-		if e.ParList.HasVargs {
-			if len(e.ParList.Names) > 0 {
-				s.add(", ")
+		s.add("if ")
+		s.expr(e.Condition, data{})
+		s.add(" then ")
+		s.expr(e.Then, data{})
+		for {
+			elseif, ok := e.Else.(*IfExpr)
+			if !ok {
+				break
 			}
-			s.add("...")
+			s.add(" elseif ")
+			s.expr(elseif.Condition, data{})
+			s.add(" then ")
+			s.expr(elseif.Then, data{})
+			e = elseif
 		}
-		s.addln(")")
-		s.chunk(e.Chunk)
-		s.tab().add("end")
+		s.add(" else ")
+		s.expr(e.Else, data{})
+	case *TypeCastExpr:
+		switch s.unparen(e.Expr).(type) {
+		case *LogicalOpExpr, *RelationalOpExpr, *StringConcatOpExpr, *ArithmeticOpExpr, *UnaryOpExpr, *TypeCastExpr, *IfExpr:
+			s.wrap(e.Expr, data{})
+		default:
+			s.expr(e.Expr, data{})
+		}
+		s.add(" :: ")
+		s.typ(e.Type, 0)
 	default:
 		panic("Unimplemented expression")
 	}
 }
 
+// table prints a table constructor, on a single line if it is short enough.
+func (s *builder) table(e *TableExpr, d data) {
+	if len(e.Fields) == 0 {
+		s.add("{}")
+		return
+	}
+	if s.config.InlineTableWidth > 0 {
+		inline := s.sub()
+		inline.add("{")
+		for i, field := range e.Fields {
+			inline.field(field, d)
+			inline.addcomma(i, len(e.Fields))
+		}
+		inline.add("}")
+		if str := inline.Str.String(); width(str) <= s.config.InlineTableWidth && !strings.Contains(str, "\n") {
+			s.add(str)
+			return
+		}
+	}
+
+	s.add("{")
+	s.Indent++
+	for i, field := range e.Fields {
+		s.addln("")
+		s.tab()
+		s.field(field, d)
+		if i < len(e.Fields)-1 || s.config.TrailingCommas {
+			s.addrune(',')
+		}
+	}
+	s.Indent--
+	s.addln("")
+	s.tab().add("}")
+}
+
+func (s *builder) field(field *Field, d data) {
+	if field.Key != nil {
+		if str, ok := keyName(field.Key); ok {
+			s.add(str.Value)
+		} else {
+			open, close := s.index(field.Key)
+			s.add(open)
+			s.expr(field.Key, d)
+			s.add(close)
+		}
+		s.add(" = ")
+	}
+	s.expr(field.Value, d)
+}
+
+func (s *builder) call(e *FuncCallExpr, d data) {
+	if e.Func != nil { // hoge.func()
+		if s.isPrefix(e.Func) {
+			s.expr(s.prefixExpr(e.Func), d)
+		} else {
+			s.wrap(e.Func, d)
+		}
+	} else { // hoge:method()
+		if s.isPrefix(e.Receiver) {
+			s.expr(s.prefixExpr(e.Receiver), data{})
+		} else {
+			s.wrap(e.Receiver, data{})
+		}
+		s.add(":")
+		s.add(e.Method)
+	}
+
+	if s.omitsCallParens(e) {
+		s.add(" ")
+		s.expr(e.Args[0], d)
+		return
+	}
+	s.add("(")
+	for i := range e.Args {
+		s.expr(e.Args[i], d)
+		s.addcomma(i, len(e.Args))
+	}
+	s.add(")")
+}
+
 func (s *builder) elseBody(elseStmt []Stmt) {
 	if len(elseStmt) > 0 {
 		if elseif, ok := elseStmt[0].(*IfStmt); ok && len(elseStmt) == 1 {
+			s.comments(elseif.LeadingComments())
 			s.tab().add("elseif ")
 			s.expr(elseif.Condition, data{})
 			s.addln(" then")
@@ -201,14 +486,44 @@ func (s *builder) elseBody(elseStmt []Stmt) {
 
 func (b *builder) chunk(c Chunk) {
 	b.Indent++
-	for _, s := range c {
-		b.stmt(s)
-	}
+	b.stmts(c, nil)
 	b.Indent--
 }
 
-func (s *builder) stmt(st Stmt) {
+// stmts prints a list of statements followed by the statements after, which
+// are not printed.
+func (b *builder) stmts(c []Stmt, after []Stmt) {
+	for i, s := range c {
+		if i > 0 && b.config.KeepBlankLines && blankLineBetween(c[i-1], s) {
+			b.addln("")
+		}
+		semicolon := true
+		switch b.config.Semicolons {
+		case SemicolonsNever:
+			semicolon = false
+		case SemicolonsRequired:
+			semicolon = false
+			for _, next := range append(c[i+1:len(c):len(c)], after...) {
+				if _, ok := next.(*CommentStmt); !ok {
+					semicolon = b.startsWithParen(next)
+					break
+				}
+			}
+		}
+		b.stmt(s, semicolon)
+	}
+}
+
+// stmt prints a statement on its own lines, semicolon tells whether it ends
+// with a semicolon.
+func (s *builder) stmt(st Stmt, semicolon bool) {
+	s.comments(st.LeadingComments())
+	if c, ok := st.(*CommentStmt); ok {
+		s.comments(c.Comments)
+		return
+	}
 	s.tab()
+	s.mark(st)
 	switch stmt := st.(type) {
 	case *AssignStmt:
 		for i, ex := range stmt.Lhs {
@@ -221,19 +536,20 @@ func (s *builder) stmt(st Stmt) {
 			s.addcomma(i, len(stmt.Rhs))
 		}
 	case *CompoundAssignStmt:
-		for i, ex := range stmt.Lhs {
-			s.expr(ex, data{})
-			s.addcomma(i, len(stmt.Lhs))
-		}
-		s.addpad(stmt.Operator)
-		for i, ex := range stmt.Rhs {
-			s.expr(ex, data{})
-			s.addcomma(i, len(stmt.Rhs))
-		}
+		s.expr(stmt.Lhs, data{})
+		s.addpad(stmt.Operator.String() + "=")
+		s.expr(stmt.Rhs, data{})
 	case *LocalAssignStmt:
 		s.add("local ")
 		for i, name := range stmt.Names {
 			s.add(name)
+			if i < len(stmt.Attribs) && stmt.Attribs[i] != "" {
+				s.add(" <" + stmt.Attribs[i] + ">")
+			}
+			if i < len(stmt.Types) && stmt.Types[i] != nil {
+				s.add(": ")
+				s.typ(stmt.Types[i], 0)
+			}
 			s.addcomma(i, len(stmt.Names))
 		}
 		if len(stmt.Exprs) > 0 {
@@ -244,31 +560,7 @@ func (s *builder) stmt(st Stmt) {
 			}
 		}
 	case *FuncCallStmt:
-		ex := stmt.Expr.(*FuncCallExpr)
-		if ex.Func != nil {
-			switch ex.Func.(type) {
-			case *IdentExpr, *AttrGetExpr:
-				s.expr(ex.Func, data{})
-			default:
-				s.wrap(ex.Func, data{})
-			}
-		} else {
-			switch ex.Receiver.(type) {
-			case *IdentExpr, *AttrGetExpr:
-				s.expr(ex.Receiver, data{})
-			default:
-				s.wrap(ex.Receiver, data{})
-			}
-			s.add(":")
-			s.add(ex.Method)
-		}
-
-		s.add("(")
-		for i := range ex.Args {
-			s.expr(ex.Args[i], data{})
-			s.addcomma(i, len(ex.Args))
-		}
-		s.add(")")
+		s.expr(stmt.Expr, data{})
 	case *DoBlockStmt:
 		s.addln("do")
 		s.chunk(stmt.Chunk)
@@ -287,44 +579,17 @@ func (s *builder) stmt(st Stmt) {
 	case *LocalFunctionStmt:
 		s.add("local function ")
 		s.add(stmt.Name)
-		s.addrune('(')
-		for i, name := range stmt.Func.ParList.Names {
-			s.add(name)
-			s.addcomma(i, len(stmt.Func.ParList.Names))
-		}
-		if stmt.Func.ParList.HasVargs {
-			if len(stmt.Func.ParList.Names) > 0 {
-				s.add(", ")
-			}
-			s.add("...")
-		}
-		s.addln(")")
-		s.chunk(stmt.Func.Chunk)
-		s.tab().add("end")
+		s.funcBody(stmt.Func)
 	case *FunctionStmt:
 		s.add("function ")
 		if stmt.Name.Func == nil {
 			s.expr(stmt.Name.Receiver, data{})
-			s.Str.WriteRune(':')
+			s.addrune(':')
 			s.add(stmt.Name.Method)
 		} else {
 			s.expr(stmt.Name.Func, data{})
 		}
-		s.addrune('(')
-		for i, name := range stmt.Func.ParList.Names {
-			s.add(name)
-			s.addcomma(i, len(stmt.Func.ParList.Names))
-		}
-
-		if stmt.Func.ParList.HasVargs {
-			if len(stmt.Func.ParList.Names) > 0 {
-				s.add(", ")
-			}
-			s.add("...")
-		}
-		s.addln(")")
-		s.chunk(stmt.Func.Chunk)
-		s.tab().add("end")
+		s.funcBody(stmt.Func)
 	case *ReturnStmt:
 		s.add("return")
 		if len(stmt.Exprs) > 0 {
@@ -348,6 +613,10 @@ func (s *builder) stmt(st Stmt) {
 	case *NumberForStmt:
 		s.add("for ")
 		s.add(stmt.Name)
+		if stmt.Type != nil {
+			s.add(": ")
+			s.typ(stmt.Type, 0)
+		}
 		s.add(" = ")
 		s.expr(stmt.Init, data{})
 		s.add(", ")
@@ -363,6 +632,10 @@ func (s *builder) stmt(st Stmt) {
 		s.add("for ")
 		for i, name := range stmt.Names {
 			s.add(name)
+			if i < len(stmt.Types) && stmt.Types[i] != nil {
+				s.add(": ")
+				s.typ(stmt.Types[i], 0)
+			}
 			s.addcomma(i, len(stmt.Names))
 		}
 		s.add(" in ")
@@ -380,8 +653,212 @@ func (s *builder) stmt(st Stmt) {
 	case *GotoStmt:
 		s.add("goto ")
 		s.add(stmt.Label)
+	case *TypeAliasStmt:
+		if stmt.Export {
+			s.add("export ")
+		}
+		s.add("type ")
+		s.add(stmt.Name)
+		s.generics(stmt.Generics, stmt.Defaults)
+		s.add(" = ")
+		s.typ(stmt.Type, 0)
+	case *BadStmt:
+		s.add("--[[bad statement]]")
 	default:
 		panic(fmt.Sprintf("unexpected statement kind: %T", stmt))
 	}
-	s.add(";\n")
+	if semicolon {
+		s.add(";")
+	}
+	if g := st.TrailingComments(); g != nil && !s.config.Compact {
+		for _, c := range g.List {
+			s.add(" ")
+			s.add(s.escapeMarks(c.Text))
+		}
+	}
+	s.add("\n")
+}
+
+// funcBody prints the signature and the body of a function, starting with
+// its generic names or its parameters.
+func (s *builder) funcBody(f *FunctionExpr) {
+	s.generics(f.Generics, nil)
+	if s.config.MaxWidth > 0 {
+		s.render(s.paramsDoc(f.ParList))
+	} else {
+		s.params(f.ParList)
+	}
+	if f.ReturnTypes != nil {
+		s.add(": ")
+		s.typeList(f.ReturnTypes)
+	}
+	s.addln("")
+	s.chunk(f.Chunk)
+	s.tab().add("end")
+}
+
+func (s *builder) params(params *ParList) {
+	s.addrune('(')
+	for i, name := range params.Names {
+		s.add(name)
+		if i < len(params.Types) && params.Types[i] != nil {
+			s.add(": ")
+			s.typ(params.Types[i], 0)
+		}
+		s.addcomma(i, len(params.Names))
+	}
+	if params.HasVargs {
+		if len(params.Names) > 0 {
+			s.add(", ")
+		}
+		s.add("...")
+		if params.VarargType != nil {
+			s.add(": ")
+			s.typ(params.VarargType, 0)
+		}
+	}
+	s.addrune(')')
+}
+
+// generics prints a list of generic names with their default types.
+func (s *builder) generics(names []string, defaults []Type) {
+	if len(names) == 0 {
+		return
+	}
+	s.add("<")
+	for i, name := range names {
+		s.add(name)
+		if i < len(defaults) && defaults[i] != nil {
+			s.add(" = ")
+			s.typ(defaults[i], 0)
+		}
+		s.addcomma(i, len(names))
+	}
+	s.add(">")
+}
+
+// Type precedences, a type is wrapped in parentheses where the precedence
+// is higher than its own.
+const (
+	typeFunction = iota
+	typeUnion
+	typeIntersection
+	typeOptional
+)
+
+func typePrecedence(t Type) int {
+	switch t.(type) {
+	case *FunctionType:
+		return typeFunction
+	case *UnionType:
+		return typeUnion
+	case *IntersectionType:
+		return typeIntersection
+	}
+	return typeOptional
+}
+
+func (s *builder) typ(t Type, precedence int) {
+	if typePrecedence(t) < precedence {
+		s.add("(")
+		defer s.add(")")
+	}
+
+	switch t := t.(type) {
+	case *TypeReference:
+		if t.Prefix != "" {
+			s.add(t.Prefix + ".")
+		}
+		s.add(t.Name)
+		if t.Params != nil {
+			s.add("<")
+			for i, param := range t.Params {
+				s.typ(param, 0)
+				s.addcomma(i, len(t.Params))
+			}
+			s.add(">")
+		}
+	case *SingletonType:
+		s.expr(t.Value, data{})
+	case *TableType:
+		s.add("{")
+		if t.Array != nil {
+			s.typ(t.Array, 0)
+		}
+		for i, field := range t.Fields {
+			if field.Key != nil {
+				s.add("[")
+				s.typ(field.Key, 0)
+				s.add("]")
+			} else {
+				s.add(field.Name)
+			}
+			s.add(": ")
+			s.typ(field.Value, 0)
+			s.addcomma(i, len(t.Fields))
+		}
+		s.add("}")
+	case *FunctionType:
+		s.generics(t.Generics, nil)
+		s.add("(")
+		for i, param := range t.Params {
+			if i < len(t.ParamNames) && t.ParamNames[i] != "" {
+				s.add(t.ParamNames[i] + ": ")
+			}
+			s.typ(param, 0)
+			s.addcomma(i, len(t.Params))
+		}
+		s.add(") -> ")
+		s.typeList(t.Returns)
+	case *UnionType:
+		for i, typ := range t.Types {
+			if i > 0 {
+				s.add(" | ")
+			}
+			s.typ(typ, typeUnion+1)
+		}
+	case *IntersectionType:
+		for i, typ := range t.Types {
+			if i > 0 {
+				s.add(" & ")
+			}
+			s.typ(typ, typeIntersection+1)
+		}
+	case *OptionalType:
+		s.typ(t.Type, typeOptional)
+		s.add("?")
+	case *TypeofType:
+		s.add("typeof(")
+		s.expr(t.Expr, data{})
+		s.add(")")
+	case *VariadicType:
+		s.add("...")
+		s.typ(t.Type, typeOptional)
+	default:
+		panic(fmt.Sprintf("unexpected type kind: %T", t))
+	}
+}
+
+// typeList prints the return types of a function, a single type is not
+// wrapped in parentheses unless it is variadic or a generic pack.
+func (s *builder) typeList(list []Type) {
+	if len(list) == 1 {
+		switch t := list[0].(type) {
+		case *VariadicType:
+		case *TypeReference:
+			if !strings.HasSuffix(t.Name, "...") {
+				s.typ(t, 0)
+				return
+			}
+		default:
+			s.typ(t, 0)
+			return
+		}
+	}
+	s.add("(")
+	for i, typ := range list {
+		s.typ(typ, 0)
+		s.addcomma(i, len(list))
+	}
+	s.add(")")
 }
@@ -10,27 +10,38 @@ func isValid(str string) bool {
 	return true
 }
 
+// isReserved reports whether str is a keyword. goto is one from Lua 5.2 on,
+// so it is never used as a name either.
 func isReserved(str string) bool {
 	switch str {
-	case "and", "break", "do", " else", "elseif", 
-		"end", "false", "for", "function", "if", 
-		"in", "local", "nil", "not", "or", "repeat", 
-		"return", "then", " true", " until", "while":
+	case "and", "break", "do", "else", "elseif",
+		"end", "false", "for", "function", "goto", "if",
+		"in", "local", "nil", "not", "or", "repeat",
+		"return", "then", "true", "until", "while":
 		return true
 	}
 	return false
 }
 
-func (s *builder) wrapIfNeeded(precedence int, associativity bool, op string, lhs Expr, rhs Expr, d data) {
-	if precedence < d.Precedence || (precedence == d.Precedence && associativity != d.Direction) {
+// wrapsUnary reports whether a unary operation of op is wrapped in
+// parentheses in the context d: under an operator binding tighter, as a
+// right operand, and in another unary operation so that two minus signs do
+// not start a comment.
+func wrapsUnary(op Op, d data) bool {
+	return op.Precedence() < d.Precedence || d.Direction || d.Parent.IsUnary()
+}
+
+func (s *builder) wrapIfNeeded(op Op, lhs Expr, rhs Expr, d data) {
+	precedence := op.Precedence()
+	if precedence < d.Precedence || (precedence == d.Precedence && op.RightAssoc() != d.Direction) {
 		s.add("(")
 		s.expr(lhs, data{precedence, false, op})
-		s.addpad(op)
+		s.addpad(op.String())
 		s.expr(rhs, data{precedence, true, op})
 		s.add(")")
 		return
 	}
 	s.expr(lhs, data{precedence, false, op})
-	s.addpad(op)
+	s.addpad(op.String())
 	s.expr(rhs, data{precedence, true, op})
 }
@@ -0,0 +1,317 @@
+package ast
+
+import "strings"
+
+// PrintCompact returns node printed with the Compact PrintConfig, with only
+// the whitespace and semicolons needed to keep its meaning.
+func PrintCompact(node Node) string {
+	str, _ := format(node, &PrintConfig{Compact: true})
+	return str
+}
+
+// MinifyOptions are the options of Minify.
+type MinifyOptions struct {
+	// RenameLocals renames local variables and parameters to the shortest
+	// names that do not collide with the variables visible where they are
+	// declared nor with a global. Globals and fields keep their names.
+	RenameLocals bool
+
+	// SourceMap is filled like PrintConfig.SourceMap if it is not nil.
+	SourceMap *SourceMap
+}
+
+// Minify returns chunk printed as small as it can be. chunk is not
+// modified.
+func Minify(chunk Chunk, options MinifyOptions) string {
+	if options.RenameLocals {
+		chunk = Clone(chunk).(Chunk)
+		renameLocals(chunk)
+	}
+	str, _ := format(chunk, &PrintConfig{Compact: true, OmitCallParens: true, SourceMap: options.SourceMap})
+	return str
+}
+
+// compact writes str, skipping its whitespace unless it separates tokens
+// that would otherwise be read as one.
+func (s *builder) compact(str string) {
+	for i := 0; i < len(str); i++ {
+		ch := str[i]
+		if ch == ' ' || ch == '\t' || ch == '\n' {
+			s.space = true
+			continue
+		}
+		s.separate(ch)
+		s.Str.WriteString(s.marked)
+		s.marked = ""
+		s.Str.WriteByte(ch)
+		s.last, s.number = ch, false
+	}
+}
+
+// literal writes a string or number literal, which is kept as it is.
+func (s *builder) literal(str string, number bool) {
+	if !s.config.Compact {
+		s.Str.WriteString(str)
+		return
+	}
+	if str == "" {
+		return
+	}
+	s.separate(str[0])
+	s.Str.WriteString(s.marked)
+	s.marked = ""
+	s.Str.WriteString(str)
+	s.last, s.number = str[len(str)-1], number
+}
+
+// separate writes a space before next if whitespace was skipped since the
+// last byte and they would be read as a single token without it.
+func (s *builder) separate(next byte) {
+	if !s.space {
+		return
+	}
+	s.space = false
+	last := s.last
+	switch {
+	case isNameByte(last) && isNameByte(next),
+		last == '-' && next == '-',                          // comment
+		last == '.' && next == '.', s.number && next == '.', // .. ... or a longer number
+		next == '=' && strings.IndexByte("<>~=", last) >= 0, // local x <const> = 1
+		last == '[' && next == '[':                          // t[ [[key]] ]
+		s.Str.WriteByte(' ')
+	}
+}
+
+func isNameByte(ch byte) bool {
+	return ch == '_' || 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || '0' <= ch && ch <= '9' || ch >= 0x80
+}
+
+// variable is a local variable or parameter, name points at each place its
+// name is written.
+type variable struct {
+	names   []*string
+	visible []*variable // variables visible where it is declared
+	fixed   bool        // the implicit self of methods, or a local _ENV
+}
+
+type resolver struct {
+	scopes    [][]*variable
+	variables []*variable
+	globals   map[string]bool
+}
+
+// renameLocals gives the local variables of chunk the shortest names that
+// keep every name referring to the same variable.
+func renameLocals(chunk Chunk) {
+	r := &resolver{globals: map[string]bool{}}
+	r.chunk(chunk)
+
+	renamed := map[*variable]string{}
+	for _, v := range r.variables {
+		if v.fixed {
+			renamed[v] = *v.names[0]
+			continue
+		}
+		taken := map[string]bool{}
+		for _, visible := range v.visible {
+			taken[renamed[visible]] = true
+		}
+		name := ""
+		for i := 0; ; i++ {
+			name = shortName(i)
+			if !taken[name] && !r.globals[name] && !isReserved(name) && !isContextual(name) {
+				break
+			}
+		}
+		renamed[v] = name
+		for _, ptr := range v.names {
+			*ptr = name
+		}
+	}
+}
+
+// shortName returns the i-th name, ordered by length.
+func shortName(i int) string {
+	const first = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ_"
+	const rest = first + "0123456789"
+	name := []byte{first[i%len(first)]}
+	for i /= len(first); i > 0; i /= len(rest) {
+		i--
+		name = append(name, rest[i%len(rest)])
+	}
+	return string(name)
+}
+
+// isContextual reports whether name has a meaning in some dialect, which
+// makes it a poor choice for a new local.
+func isContextual(name string) bool {
+	switch name {
+	case "self", "goto", "continue", "type", "export", "typeof":
+		return true
+	}
+	return false
+}
+
+func (r *resolver) open()  { r.scopes = append(r.scopes, nil) }
+func (r *resolver) close() { r.scopes = r.scopes[:len(r.scopes)-1] }
+
+func (r *resolver) declare(name *string, fixed bool) {
+	// Since Lua 5.2 globals are fields of the _ENV in scope, renaming a local
+	// one would change what they refer to.
+	v := &variable{names: []*string{name}, fixed: fixed || *name == "_ENV"}
+	for _, scope := range r.scopes {
+		v.visible = append(v.visible, scope...)
+	}
+	scope := &r.scopes[len(r.scopes)-1]
+	*scope = append(*scope, v)
+	r.variables = append(r.variables, v)
+}
+
+func (r *resolver) lookup(name string) *variable {
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		scope := r.scopes[i]
+		for j := len(scope) - 1; j >= 0; j-- {
+			if *scope[j].names[0] == name {
+				return scope[j]
+			}
+		}
+	}
+	return nil
+}
+
+func (r *resolver) chunk(c Chunk) {
+	r.open()
+	for _, st := range c {
+		r.stmt(st)
+	}
+	r.close()
+}
+
+func (r *resolver) stmt(st Stmt) {
+	switch st := st.(type) {
+	case *AssignStmt:
+		r.exprs(st.Rhs)
+		r.exprs(st.Lhs)
+	case *CompoundAssignStmt:
+		r.node(st.Lhs)
+		r.node(st.Rhs)
+	case *LocalAssignStmt:
+		r.exprs(st.Exprs)
+		for _, t := range st.Types {
+			if t != nil {
+				r.node(t)
+			}
+		}
+		for i := range st.Names {
+			r.declare(&st.Names[i], false)
+		}
+	case *FuncCallStmt:
+		r.node(st.Expr)
+	case *DoBlockStmt:
+		r.chunk(st.Chunk)
+	case *WhileStmt:
+		r.node(st.Condition)
+		r.chunk(st.Chunk)
+	case *RepeatStmt:
+		// The condition sees the locals of the body.
+		r.open()
+		for _, st := range st.Chunk {
+			r.stmt(st)
+		}
+		r.node(st.Condition)
+		r.close()
+	case *IfStmt:
+		r.node(st.Condition)
+		r.chunk(st.Then)
+		r.chunk(st.Else)
+	case *NumberForStmt:
+		if st.Type != nil {
+			r.node(st.Type)
+		}
+		r.node(st.Init)
+		r.node(st.Limit)
+		if st.Step != nil {
+			r.node(st.Step)
+		}
+		r.open()
+		r.declare(&st.Name, false)
+		r.chunk(st.Chunk)
+		r.close()
+	case *GenericForStmt:
+		for _, t := range st.Types {
+			if t != nil {
+				r.node(t)
+			}
+		}
+		r.exprs(st.Exprs)
+		r.open()
+		for i := range st.Names {
+			r.declare(&st.Names[i], false)
+		}
+		r.chunk(st.Chunk)
+		r.close()
+	case *LocalFunctionStmt:
+		r.declare(&st.Name, false)
+		r.function(st.Func, false)
+	case *FunctionStmt:
+		if st.Name.Func != nil {
+			r.node(st.Name.Func)
+		} else {
+			r.node(st.Name.Receiver)
+		}
+		r.function(st.Func, st.Name.Func == nil)
+	case *ReturnStmt:
+		r.exprs(st.Exprs)
+	case *TypeAliasStmt:
+		for _, t := range st.Defaults {
+			if t != nil {
+				r.node(t)
+			}
+		}
+		r.node(st.Type)
+	}
+}
+
+func (r *resolver) function(f *FunctionExpr, method bool) {
+	r.open()
+	if method {
+		self := "self"
+		r.declare(&self, true)
+	}
+	for i := range f.ParList.Names {
+		r.declare(&f.ParList.Names[i], false)
+	}
+	Inspect(f.ParList, r.inspect)
+	for _, t := range f.ReturnTypes {
+		r.node(t)
+	}
+	r.chunk(f.Chunk)
+	r.close()
+}
+
+func (r *resolver) exprs(list []Expr) {
+	for _, e := range list {
+		r.node(e)
+	}
+}
+
+// node resolves the names of an expression or a type, which may hold
+// expressions in typeof.
+func (r *resolver) node(n Node) {
+	Inspect(n, r.inspect)
+}
+
+func (r *resolver) inspect(n Node) bool {
+	switch n := n.(type) {
+	case *IdentExpr:
+		if v := r.lookup(n.Value); v != nil {
+			v.names = append(v.names, &n.Value)
+		} else {
+			r.globals[n.Value] = true
+		}
+	case *FunctionExpr:
+		r.function(n, false)
+		return false
+	}
+	return true
+}
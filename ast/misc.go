@@ -1,19 +1,41 @@
 package ast
 
 type Field struct {
+	NodeBase
+
 	Key   Expr
 	Value Expr
 }
 
 type ParList struct {
+	NodeBase
+
 	HasVargs bool
 	Names    []string
+	// Types holds the Luau type annotation of each name, nil for names
+	// without one. It is nil when no name has one.
+	Types      []Type
+	VarargType Type // Luau type of the varargs, if annotated
 }
 
 type FuncName struct {
+	NodeBase
+
 	Func     Expr
 	Receiver Expr
 	Method   string
 }
 
-type Chunk []Stmt
\ No newline at end of file
+type Chunk []Stmt
+
+func (c Chunk) astNode() {}
+
+// File is a parsed source file. Comments holds every comment of the source
+// in order, the same ones that are attached to the statements of Chunk,
+// including the comments inside expressions which are attached to the
+// statement around them.
+type File struct {
+	Name     string
+	Chunk    Chunk
+	Comments []*Comment
+}
@@ -0,0 +1,107 @@
+package ast
+
+import "strconv"
+
+// Op is the operator of a unary or binary operation, or of a compound
+// assignment. The zero Op is not a valid operator.
+type Op int
+
+const (
+	OpAdd      Op = iota + 1 // +
+	OpSub                    // -
+	OpMul                    // *
+	OpDiv                    // /
+	OpFloorDiv               // //
+	OpMod                    // %
+	OpPow                    // ^
+	OpConcat                 // ..
+	OpBand                   // &
+	OpBor                    // |
+	OpBxor                   // ~
+	OpShl                    // <<
+	OpShr                    // >>
+	OpEq                     // ==
+	OpNe                     // ~=
+	OpLt                     // <
+	OpLe                     // <=
+	OpGt                     // >
+	OpGe                     // >=
+	OpAnd                    // and
+	OpOr                     // or
+
+	// Unary operators.
+	OpNeg  // -
+	OpNot  // not
+	OpLen  // #
+	OpBnot // ~
+)
+
+var ops = [...]struct {
+	text       string
+	precedence int
+}{
+	OpAdd:      {"+", 9},
+	OpSub:      {"-", 9},
+	OpMul:      {"*", 10},
+	OpDiv:      {"/", 10},
+	OpFloorDiv: {"//", 10},
+	OpMod:      {"%", 10},
+	OpPow:      {"^", 12},
+	OpConcat:   {"..", 8},
+	OpBand:     {"&", 6},
+	OpBor:      {"|", 4},
+	OpBxor:     {"~", 5},
+	OpShl:      {"<<", 7},
+	OpShr:      {">>", 7},
+	OpEq:       {"==", 3},
+	OpNe:       {"~=", 3},
+	OpLt:       {"<", 3},
+	OpLe:       {"<=", 3},
+	OpGt:       {">", 3},
+	OpGe:       {">=", 3},
+	OpAnd:      {"and", 2},
+	OpOr:       {"or", 1},
+	OpNeg:      {"-", 11},
+	OpNot:      {"not", 11},
+	OpLen:      {"#", 11},
+	OpBnot:     {"~", 11},
+}
+
+func (op Op) valid() bool {
+	return op > 0 && int(op) < len(ops)
+}
+
+// String returns the operator as written in the source, like "+" or "not".
+func (op Op) String() string {
+	if !op.valid() {
+		return "Op(" + strconv.Itoa(int(op)) + ")"
+	}
+	return ops[op].text
+}
+
+// Precedence returns how tightly the operator binds, from 1 for or to 12
+// for ^. Unary operators bind tighter than every binary operator but ^.
+// It returns 0 for an invalid Op.
+func (op Op) Precedence() int {
+	if !op.valid() {
+		return 0
+	}
+	return ops[op].precedence
+}
+
+// RightAssoc reports whether the operator is right associative, like .. and
+// ^ are.
+func (op Op) RightAssoc() bool {
+	return op == OpConcat || op == OpPow
+}
+
+// IsUnary reports whether the operator takes a single operand.
+func (op Op) IsUnary() bool {
+	return op >= OpNeg && op <= OpBnot
+}
+
+// IsComparison reports whether the operator is a comparison, which is the
+// operator of a RelationalOpExpr.
+func (op Op) IsComparison() bool {
+	return op >= OpEq && op <= OpGe
+}
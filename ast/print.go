@@ -1,13 +1,15 @@
 package ast
 
 import (
+	"errors"
+	"math"
 	"strconv"
 	"strings"
 
 	"github.com/notnoobmaster/luautil"
 )
 
-func (c Chunk) String() string { 
+func (c Chunk) String() string {
 	s := &builder{
 		Str:    &strings.Builder{},
 		Indent: -1, // Accounting for the fact that each chunk call increments Indent by one
@@ -23,9 +25,112 @@ func (v *TrueExpr) String() string { return "true" }
 func (v *FalseExpr) String() string { return "false" }
 func (v *IdentExpr) String() string { return v.Value }
 func (v *Comma3Expr) String() string { return "..."}
+func (v *BadExpr) String() string { return "--[[bad expression]]" }
 
 func (v *NumberExpr) String() string {
-	return strconv.FormatFloat(v.Value, 'f', -1, 64)
+	if v.Raw != "" && v.rawMatches() {
+		return v.Raw
+	}
+	return v.Canonical()
+}
+
+// rawMatches reports whether Raw spells a number of the same type and value,
+// it does not after the number is changed.
+func (v *NumberExpr) rawMatches() bool {
+	n, ok := parseNumber(v.Raw)
+	return ok && n.Integer == v.Integer && sameNumber(&n, v)
+}
+
+// sameNumber reports whether a and b have the same value. Like in Lua an
+// integer is the same as a float of equal value.
+func sameNumber(a, b *NumberExpr) bool {
+	if a.Integer && b.Integer {
+		return a.Int == b.Int
+	}
+	x, y := a.Value, b.Value
+	if a.Integer {
+		x = float64(a.Int)
+	}
+	if b.Integer {
+		y = float64(b.Int)
+	}
+	return x == y || math.IsNaN(x) && math.IsNaN(y)
+}
+
+// parseNumber reads a number literal the way the parser does, ok is false
+// if raw is not one.
+func parseNumber(raw string) (n NumberExpr, ok bool) {
+	str := strings.ToLower(strings.ReplaceAll(raw, "_", ""))
+	if str == "" || str[0] != '.' && (str[0] < '0' || str[0] > '9') {
+		return n, false
+	}
+	base := 10
+	switch {
+	case strings.HasPrefix(str, "0b"):
+		base = 2
+	case strings.HasPrefix(str, "0o"):
+		base = 8
+	case strings.HasPrefix(str, "0x"):
+		if strings.ContainsAny(str, ".p") {
+			if !strings.Contains(str, "p") {
+				str += "p0"
+			}
+			return parseFloat(str)
+		}
+		base = 16
+	default:
+		if i, err := strconv.ParseInt(str, 10, 64); err == nil {
+			return NumberExpr{Value: float64(i), Integer: true, Int: i}, true
+		}
+		return parseFloat(str)
+	}
+	// Prefixed integers wrap around on overflow.
+	var val uint64
+	for _, c := range str[2:] {
+		digit, err := strconv.ParseUint(string(c), base, 8)
+		if err != nil {
+			return n, false
+		}
+		val = val*uint64(base) + digit
+	}
+	return NumberExpr{Value: float64(int64(val)), Integer: true, Int: int64(val)}, len(str) > 2
+}
+
+func parseFloat(str string) (n NumberExpr, ok bool) {
+	val, err := strconv.ParseFloat(str, 64)
+	if err != nil && !errors.Is(err, strconv.ErrRange) {
+		return n, false
+	}
+	return NumberExpr{Value: val}, true
+}
+
+// Canonical returns the number spelled in decimal, with a fraction for
+// floats so they stay floats. Negative integers, which only come from
+// wrapped around hexadecimal numbers, are spelled in hexadecimal.
+func (v *NumberExpr) Canonical() string {
+	if v.Integer {
+		if v.Int < 0 {
+			return "0x" + strconv.FormatUint(uint64(v.Int), 16)
+		}
+		return strconv.FormatInt(v.Int, 10)
+	}
+	switch {
+	case math.IsNaN(v.Value):
+		return "(0/0)"
+	case math.IsInf(v.Value, 1):
+		return "1e999"
+	case math.IsInf(v.Value, -1):
+		return "-1e999"
+	}
+	format := byte('f')
+	if abs := math.Abs(v.Value); abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		format = 'g'
+	}
+	str := strconv.FormatFloat(v.Value, format, -1, 64)
+	if !strings.ContainsAny(str, ".e") {
+		str += ".0"
+	}
+	return str
 }
 
 func (v *StringExpr) String() string {
@@ -35,55 +140,79 @@ func (v *StringExpr) String() string {
 // We pass the value to b.expr because we need to know the indentation level and carry some state.
 
 func (e *AttrGetExpr) String() string {
-	b := &builder{&strings.Builder{}, 0}
+	b := &builder{Str: &strings.Builder{}}
 	b.expr(e, data{})
 	return b.Str.String()
 }
 
 func (e *TableExpr) String() string {
-	b := &builder{&strings.Builder{}, 0}
+	b := &builder{Str: &strings.Builder{}}
 	b.expr(e, data{})
 	return b.Str.String()
 }
 
 func (e *FuncCallExpr) String() string {
-	b := &builder{&strings.Builder{}, 0}
+	b := &builder{Str: &strings.Builder{}}
 	b.expr(e, data{})
 	return b.Str.String()
 }
 
 func (e *LogicalOpExpr) String() string {
-	b := &builder{&strings.Builder{}, 0}
+	b := &builder{Str: &strings.Builder{}}
 	b.expr(e, data{})
 	return b.Str.String()
 }
 
 func (e *RelationalOpExpr) String() string {
-	b := &builder{&strings.Builder{}, 0}
+	b := &builder{Str: &strings.Builder{}}
 	b.expr(e, data{})
 	return b.Str.String()
 }
 
 func (e *StringConcatOpExpr) String() string {
-	b := &builder{&strings.Builder{}, 0}
+	b := &builder{Str: &strings.Builder{}}
 	b.expr(e, data{})
 	return b.Str.String()
 }
 
 func (e *ArithmeticOpExpr) String() string {
-	b := &builder{&strings.Builder{}, 0}
+	b := &builder{Str: &strings.Builder{}}
 	b.expr(e, data{})
 	return b.Str.String()
 }
 
 func (e *UnaryOpExpr) String() string {
-	b := &builder{&strings.Builder{}, 0}
+	b := &builder{Str: &strings.Builder{}}
+	b.expr(e, data{})
+	return b.Str.String()
+}
+
+func (e *ParenExpr) String() string {
+	b := &builder{Str: &strings.Builder{}}
 	b.expr(e, data{})
 	return b.Str.String()
 }
 
 func (e *FunctionExpr) String() string {
-	b := &builder{&strings.Builder{}, 0}
+	b := &builder{Str: &strings.Builder{}}
+	b.expr(e, data{})
+	return b.Str.String()
+}
+
+func (e *InterpolatedStringExpr) String() string {
+	b := &builder{Str: &strings.Builder{}}
+	b.expr(e, data{})
+	return b.Str.String()
+}
+
+func (e *IfExpr) String() string {
+	b := &builder{Str: &strings.Builder{}}
+	b.expr(e, data{})
+	return b.Str.String()
+}
+
+func (e *TypeCastExpr) String() string {
+	b := &builder{Str: &strings.Builder{}}
 	b.expr(e, data{})
 	return b.Str.String()
 }
@@ -91,103 +220,138 @@ func (e *FunctionExpr) String() string {
 // Statements
 
 func (s *AssignStmt) String() string {
-	b := &builder{&strings.Builder{}, 0}
-	b.stmt(s)
+	b := &builder{Str: &strings.Builder{}}
+	b.stmt(s, true)
 	return b.Str.String()
 }
 
 func (s *CompoundAssignStmt) String() string {
-	b := &builder{&strings.Builder{}, 0}
-	b.stmt(s)
+	b := &builder{Str: &strings.Builder{}}
+	b.stmt(s, true)
 	return b.Str.String()
 }
 
 func (s *LocalAssignStmt) String() string {
-	b := &builder{&strings.Builder{}, 0}
-	b.stmt(s)
+	b := &builder{Str: &strings.Builder{}}
+	b.stmt(s, true)
 	return b.Str.String()
 }
 
 func (s *FuncCallStmt) String() string {
-	b := &builder{&strings.Builder{}, 0}
-	b.stmt(s)
+	b := &builder{Str: &strings.Builder{}}
+	b.stmt(s, true)
 	return b.Str.String()
 }
 
 func (s *DoBlockStmt) String() string {
-	b := &builder{&strings.Builder{}, 0}
-	b.stmt(s)
+	b := &builder{Str: &strings.Builder{}}
+	b.stmt(s, true)
 	return b.Str.String()
 }
 
 func (s *WhileStmt) String() string {
-	b := &builder{&strings.Builder{}, 0}
-	b.stmt(s)
+	b := &builder{Str: &strings.Builder{}}
+	b.stmt(s, true)
 	return b.Str.String()
 }
 
 func (s *RepeatStmt) String() string {
-	b := &builder{&strings.Builder{}, 0}
-	b.stmt(s)
+	b := &builder{Str: &strings.Builder{}}
+	b.stmt(s, true)
 	return b.Str.String()
 }
 
 func (s *IfStmt) String() string {
-	b := &builder{&strings.Builder{}, 0}
-	b.stmt(s)
+	b := &builder{Str: &strings.Builder{}}
+	b.stmt(s, true)
 	return b.Str.String()
 }
 
 func (s *NumberForStmt) String() string {
-	b := &builder{&strings.Builder{}, 0}
-	b.stmt(s)
+	b := &builder{Str: &strings.Builder{}}
+	b.stmt(s, true)
 	return b.Str.String()
 }
 
 func (s *GenericForStmt) String() string {
-	b := &builder{&strings.Builder{}, 0}
-	b.stmt(s)
+	b := &builder{Str: &strings.Builder{}}
+	b.stmt(s, true)
 	return b.Str.String()
 }
 
 func (s *LocalFunctionStmt) String() string {
-	b := &builder{&strings.Builder{}, 0}
-	b.stmt(s)
+	b := &builder{Str: &strings.Builder{}}
+	b.stmt(s, true)
 	return b.Str.String()
 }
 
 func (s *FunctionStmt) String() string {
-	b := &builder{&strings.Builder{}, 0}
-	b.stmt(s)
+	b := &builder{Str: &strings.Builder{}}
+	b.stmt(s, true)
 	return b.Str.String()
 }
 
 func (s *ReturnStmt) String() string {
-	b := &builder{&strings.Builder{}, 0}
-	b.stmt(s)
+	b := &builder{Str: &strings.Builder{}}
+	b.stmt(s, true)
 	return b.Str.String()
 }
 
 func (s *BreakStmt) String() string {
-	b := &builder{&strings.Builder{}, 0}
-	b.stmt(s)
+	b := &builder{Str: &strings.Builder{}}
+	b.stmt(s, true)
 	return b.Str.String()
 }
 
 func (s *ContinueStmt) String() string {
-	b := &builder{&strings.Builder{}, 0}
-	b.stmt(s)
+	b := &builder{Str: &strings.Builder{}}
+	b.stmt(s, true)
 	return b.Str.String()
 }
 
 func (s *LabelStmt) String() string {
-	b := &builder{&strings.Builder{}, 0}
-	b.stmt(s)
+	b := &builder{Str: &strings.Builder{}}
+	b.stmt(s, true)
 	return b.Str.String()
 }
 
 func (s *GotoStmt) String() string {
-	b := &builder{&strings.Builder{}, 0}
-	b.stmt(s)
+	b := &builder{Str: &strings.Builder{}}
+	b.stmt(s, true)
+	return b.Str.String()
+}
+func (s *CommentStmt) String() string {
+	b := &builder{Str: &strings.Builder{}}
+	b.stmt(s, true)
+	return b.Str.String()
+}
+
+func (s *TypeAliasStmt) String() string {
+	b := &builder{Str: &strings.Builder{}}
+	b.stmt(s, true)
+	return b.Str.String()
+}
+
+func (s *BadStmt) String() string {
+	b := &builder{Str: &strings.Builder{}}
+	b.stmt(s, true)
 	return b.Str.String()
-}
\ No newline at end of file
+}
+
+// Types
+
+func typeString(t Type) string {
+	b := &builder{Str: &strings.Builder{}}
+	b.typ(t, 0)
+	return b.Str.String()
+}
+
+func (t *TypeReference) String() string    { return typeString(t) }
+func (t *SingletonType) String() string    { return typeString(t) }
+func (t *TableType) String() string        { return typeString(t) }
+func (t *FunctionType) String() string     { return typeString(t) }
+func (t *UnionType) String() string        { return typeString(t) }
+func (t *IntersectionType) String() string { return typeString(t) }
+func (t *OptionalType) String() string     { return typeString(t) }
+func (t *TypeofType) String() string       { return typeString(t) }
+func (t *VariadicType) String() string     { return typeString(t) }
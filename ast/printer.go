@@ -0,0 +1,189 @@
+package ast
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// SemicolonPolicy tells when statements end with a semicolon.
+type SemicolonPolicy int
+
+const (
+	// SemicolonsAlways ends every statement with a semicolon.
+	SemicolonsAlways SemicolonPolicy = iota
+	// SemicolonsNever never writes semicolons, even where the next statement
+	// starting with a parenthesis makes the code mean something else.
+	SemicolonsNever
+	// SemicolonsRequired only ends statements followed by one starting with
+	// a parenthesis, which would otherwise be read as a call.
+	SemicolonsRequired
+)
+
+// PrintConfig controls the layout of the code written by Fprint. The zero
+// PrintConfig gives the output of the String methods.
+type PrintConfig struct {
+	// Indent is the number of spaces of each indentation level, tabs are
+	// used when it is 0.
+	Indent int
+
+	Semicolons SemicolonPolicy
+
+	// Quote is the preferred quote of strings, '"' or '\''. The other one
+	// is used for strings that contain more of it. When Quote is 0 strings
+	// are always double quoted.
+	Quote byte
+
+	// OmitCallParens writes calls whose only argument is a string or a
+	// table without parentheses, like require "x".
+	OmitCallParens bool
+
+	// InlineTableWidth is the longest a table may be to be written on a
+	// single line, tables are always written one field per line when it is
+	// 0.
+	InlineTableWidth int
+
+	// TrailingCommas adds a comma after the last field of tables written
+	// one field per line.
+	TrailingCommas bool
+
+	// KeepBlankLines keeps a blank line between statements that were
+	// separated by blank lines in the source.
+	KeepBlankLines bool
+
+	// MaxWidth is the width lines are kept within where possible, by
+	// breaking argument and parameter lists, table constructors and chains
+	// of binary operators over several lines. Tables are written on a single
+	// line when they fit, instead of following InlineTableWidth. Tabs count
+	// as 4 columns. Lines are not wrapped when it is 0.
+	MaxWidth int
+
+	// SourceMap, if not nil, is filled with the mappings of the printed code
+	// back to the source.
+	SourceMap *SourceMap
+
+	// Compact writes the code on a single line, with only the spaces and
+	// semicolons needed to keep its meaning and without comments. The
+	// options about the layout are ignored.
+	Compact bool
+
+	// NormalizeNumbers writes numbers in their canonical spelling instead of
+	// the one they had in the source.
+	NormalizeNumbers bool
+
+	// KeepParens keeps the parentheses of the source that do not change the
+	// meaning of the code, like those of (a + b) * c or (x). They are
+	// dropped otherwise, and added back where the precedence of operators
+	// needs them.
+	KeepParens bool
+
+	// KeepQuotes writes strings with the quotes or long brackets they had in
+	// the source instead of following Quote.
+	KeepQuotes bool
+}
+
+// Fprint writes node formatted according to config to w. node is a Chunk,
+// a Stmt, an Expr or a Type. A nil config is the same as the zero
+// PrintConfig.
+func Fprint(w io.Writer, node Node, config *PrintConfig) error {
+	str, err := format(node, config)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, str)
+	return err
+}
+
+// Format returns node formatted according to config, like Fprint.
+func Format(node Node, config *PrintConfig) (string, error) {
+	return format(node, config)
+}
+
+func format(node Node, config *PrintConfig) (string, error) {
+	b := &builder{Str: &strings.Builder{}}
+	if config != nil {
+		b.config = *config
+	}
+	if b.config.SourceMap != nil {
+		b.marks = &[]Position{}
+	}
+	if b.config.Compact {
+		b.config.Semicolons = SemicolonsRequired
+		b.config.InlineTableWidth = 0
+		b.config.TrailingCommas = false
+		b.config.KeepBlankLines = false
+		b.config.MaxWidth = 0
+	}
+	switch n := node.(type) {
+	case Chunk:
+		b.Indent = -1 // chunk increments Indent
+		b.chunk(n)
+	case Stmt:
+		b.stmt(n, b.config.Semicolons == SemicolonsAlways)
+	case Expr:
+		b.expr(n, data{})
+	case Type:
+		b.typ(n, 0)
+	default:
+		return "", fmt.Errorf("ast: cannot print %T", node)
+	}
+	if b.config.SourceMap != nil {
+		str, mappings := unmark(b.Str.String(), *b.marks)
+		b.config.SourceMap.Mappings = mappings
+		return str, nil
+	}
+	return b.Str.String(), nil
+}
+
+// startsWithParen reports whether stmt is printed starting with a
+// parenthesis.
+func (s *builder) startsWithParen(stmt Stmt) bool {
+	var expr Expr
+	switch stmt := stmt.(type) {
+	case *FuncCallStmt:
+		expr = stmt.Expr
+	case *AssignStmt:
+		expr = stmt.Lhs[0]
+	case *CompoundAssignStmt:
+		expr = stmt.Lhs
+	default:
+		return false
+	}
+	for {
+		var prefix Expr
+		switch e := expr.(type) {
+		case *IdentExpr:
+			return false
+		case *AttrGetExpr:
+			if str, ok := s.unparen(e.Object).(*StringExpr); ok && str.Value == "" {
+				return false
+			}
+			prefix = e.Object
+		case *FuncCallExpr:
+			prefix = e.Func
+			if prefix == nil {
+				prefix = e.Receiver
+			}
+		default:
+			return true
+		}
+		// Prefixes other than names and indexing are wrapped.
+		switch prefix = s.unparen(prefix); prefix.(type) {
+		case *IdentExpr, *AttrGetExpr:
+			expr = prefix
+		default:
+			return true
+		}
+	}
+}
+
+// blankLineBetween reports whether the source had a blank line between the
+// statements prev and next.
+func blankLineBetween(prev, next Stmt) bool {
+	start := next.Pos()
+	if g := next.LeadingComments(); g != nil && len(g.List) > 0 {
+		start = g.List[0].Pos()
+	}
+	end := prev.End()
+	return end.Line > 0 && start.Line > end.Line+1
+}
@@ -0,0 +1,174 @@
+package ast
+
+import (
+	"bytes"
+	"strings"
+)
+
+// FormatRange reformats the lines from startLine to endLine of src, which
+// chunk was parsed from, and leaves the rest of src as it is. The smallest
+// run of statements of a single block covering the lines is printed with
+// config again, indented like the first of them was, and replaces their
+// lines in a copy of src. src is returned unchanged when the lines hold no
+// statement.
+func FormatRange(src []byte, chunk Chunk, startLine, endLine int, config *PrintConfig) ([]byte, error) {
+	lines, ends := lineOffsets(src)
+	r := &rangeFormatter{src: src, lines: lines, ends: ends, start: startLine, end: endLine}
+	stmts, after := r.enclosing(chunk, false)
+	if len(stmts) == 0 {
+		return append([]byte{}, src...), nil
+	}
+
+	first, _ := stmtLines(stmts[0])
+	_, last := stmtLines(stmts[len(stmts)-1])
+	from := lines[first-1]
+	to := r.lineEnd(last)
+	prefix := src[from:]
+	prefix = prefix[:len(prefix)-len(bytes.TrimLeft(prefix, " \t"))]
+
+	b := &builder{Str: &strings.Builder{}}
+	if config != nil {
+		b.config = *config
+	}
+	b.config.Compact = false
+	b.prefix = string(prefix)
+	b.stmts(stmts, after)
+	out := strings.TrimSuffix(b.Str.String(), "\n")
+	// Long comments keep the newlines of the source, all lines end like
+	// the first one of the source.
+	out = strings.ReplaceAll(newlines.Replace(out), "\n", r.newline())
+
+	result := make([]byte, 0, len(src)+len(out))
+	result = append(result, src[:from]...)
+	result = append(result, out...)
+	return append(result, src[to:]...), nil
+}
+
+type rangeFormatter struct {
+	src        []byte
+	lines      []int // offset of the start of each line
+	ends       []int // offset of the end of each line, before its newline
+	start, end int
+}
+
+// enclosing returns the statements of c covering the range, or those of a
+// block nested in them if it covers the range by itself, along with the
+// statements following them. It returns nil if they share their lines
+// with other code, the caller then formats its own statement. An elseif
+// never returns its own statement, which is printed as a whole if.
+func (r *rangeFormatter) enclosing(c Chunk, elseif bool) (stmts, after []Stmt) {
+	var from, to int
+	for i, st := range c {
+		first, last := stmtLines(st)
+		if last < r.start {
+			from = i + 1
+		}
+		if first <= r.end {
+			to = i + 1
+		}
+	}
+	if from >= to {
+		return nil, nil
+	}
+	stmts, after = c[from:to], c[to:]
+
+	if len(stmts) == 1 {
+		var found []Stmt
+		var foundAfter []Stmt
+		Inspect(stmts[0], func(n Node) bool {
+			block, ok := n.(Chunk)
+			if !ok {
+				return found == nil
+			}
+			if len(block) > 0 && found == nil {
+				first, _ := stmtLines(block[0])
+				_, last := stmtLines(block[len(block)-1])
+				if first <= r.start && r.end <= last {
+					ifStmt, ok := stmts[0].(*IfStmt)
+					_, nested := block[0].(*IfStmt)
+					elseif := ok && nested && len(ifStmt.Else) == 1 && block[0] == ifStmt.Else[0]
+					found, foundAfter = r.enclosing(block, elseif)
+				}
+			}
+			return false
+		})
+		if found != nil {
+			return found, foundAfter
+		}
+	}
+	if elseif || !r.alone(stmts) {
+		return nil, nil
+	}
+	return stmts, after
+}
+
+// alone reports whether the lines of stmts hold nothing else.
+func (r *rangeFormatter) alone(stmts []Stmt) bool {
+	first := stmts[0]
+	start := first.Pos()
+	if g := first.LeadingComments(); g != nil && len(g.List) > 0 {
+		start = g.List[0].Pos()
+	}
+	before := r.src[r.lines[start.Line-1]:start.Offset]
+	if len(bytes.TrimLeft(before, " \t")) > 0 {
+		return false
+	}
+
+	last := stmts[len(stmts)-1]
+	end := last.End()
+	if g := last.TrailingComments(); g != nil && len(g.List) > 0 {
+		end = g.List[len(g.List)-1].End()
+	}
+	rest := bytes.TrimLeft(r.src[end.Offset:r.lineEnd(end.Line)], " \t\r;")
+	return len(rest) == 0
+}
+
+// lineEnd returns the offset of the end of a line, before its newline.
+func (r *rangeFormatter) lineEnd(line int) int {
+	if line > len(r.ends) {
+		return len(r.src)
+	}
+	return r.ends[line-1]
+}
+
+// newline returns the first newline of the source, which the printed lines
+// end with.
+func (r *rangeFormatter) newline() string {
+	if len(r.lines) < 2 {
+		return "\n"
+	}
+	return string(r.src[r.ends[0]:r.lines[1]])
+}
+
+// stmtLines returns the first and last line of a statement, including its
+// comments.
+func stmtLines(st Stmt) (first, last int) {
+	first, last = st.Pos().Line, st.End().Line
+	if g := st.LeadingComments(); g != nil && len(g.List) > 0 {
+		first = g.List[0].Pos().Line
+	}
+	if g := st.TrailingComments(); g != nil && len(g.List) > 0 {
+		last = g.List[len(g.List)-1].End().Line
+	}
+	return first, last
+}
+
+var newlines = strings.NewReplacer("\r\n", "\n", "\n\r", "\n", "\r", "\n")
+
+// lineOffsets returns the offsets of the start and the end of each line
+// of src. Like the scanner it takes \n, \r, \r\n and \n\r as newlines.
+func lineOffsets(src []byte) (starts, ends []int) {
+	starts = []int{0}
+	for i := 0; i < len(src); i++ {
+		ch := src[i]
+		if ch != '\n' && ch != '\r' {
+			continue
+		}
+		ends = append(ends, i)
+		if i+1 < len(src) && src[i+1] != ch && (src[i+1] == '\n' || src[i+1] == '\r') {
+			i++
+		}
+		starts = append(starts, i+1)
+	}
+	return starts, append(ends, len(src))
+}
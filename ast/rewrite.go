@@ -0,0 +1,273 @@
+package ast
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// ApplyFunc is called by Apply with a Cursor at the current node, its
+// result controls the traversal.
+type ApplyFunc func(*Cursor) bool
+
+// Apply rewrites the syntax tree rooted at root in place and returns it,
+// root itself may have been replaced. Each node, nil ones included, is
+// passed to pre before its children and to post after them, either of them
+// may be nil. When pre returns false the children of the node are skipped
+// and post is not called for it, when post returns false Apply stops at
+// once.
+//
+// Children are visited in source order, only the fields holding nodes are.
+// Nodes added through the Cursor are not visited, but the children of a
+// node replaced by pre are.
+func Apply(root Node, pre, post ApplyFunc) Node {
+	holder := &rootHolder{root}
+	r := &rewriter{pre: pre, post: post}
+	r.visit(&Cursor{parent: holder, name: "Node", index: -1, node: root})
+	return holder.Node
+}
+
+// rootHolder is the parent of the root of Apply.
+type rootHolder struct {
+	Node
+}
+
+// chunkRoot holds a Chunk that is not the field of another node, so that
+// its statements can be changed in place.
+type chunkRoot struct {
+	Chunk Chunk
+}
+
+func (r *chunkRoot) astNode() {}
+
+// Cursor is the place of the current node of Apply in the tree: the field
+// Name of Parent, and its Index if the field is a slice. The statements of
+// a Chunk given to Apply have the Chunk as their parent and "Chunk" as the
+// name of their field.
+type Cursor struct {
+	parent Node
+	name   string
+	index  int // -1 when the field is not a slice
+	skip   int // elements after the current one added or removed
+	node   Node
+}
+
+// Node returns the current node.
+func (c *Cursor) Node() Node { return c.node }
+
+// Parent returns the node holding the current one.
+func (c *Cursor) Parent() Node {
+	if r, ok := c.parent.(*chunkRoot); ok {
+		return r.Chunk
+	}
+	return c.parent
+}
+
+// Name returns the name of the field of Parent holding the current node.
+func (c *Cursor) Name() string { return c.name }
+
+// Index returns the index of the current node in the slice holding it, or
+// -1 if it is not in a slice. Inserting a node before it increments it.
+func (c *Cursor) Index() int { return c.index }
+
+// Replace puts n in place of the current node.
+func (c *Cursor) Replace(n Node) {
+	v := c.field()
+	if c.index >= 0 {
+		v = v.Index(c.index)
+	}
+	v.Set(nodeValue(n, v.Type()))
+	c.node = n
+}
+
+// Delete removes the current node from its slice, it panics if the node is
+// not in one.
+func (c *Cursor) Delete() {
+	v := c.slice("Delete")
+	n := v.Len()
+	reflect.Copy(v.Slice(c.index, n), v.Slice(c.index+1, n))
+	v.Index(n - 1).Set(reflect.Zero(v.Type().Elem()))
+	v.SetLen(n - 1)
+	c.skip--
+}
+
+// InsertBefore adds n to the slice of the current node, before it. It
+// panics if the node is not in a slice.
+func (c *Cursor) InsertBefore(n Node) {
+	insert(c.slice("InsertBefore"), c.index, n)
+	c.index++
+}
+
+// InsertAfter adds n to the slice of the current node, after it. It panics
+// if the node is not in a slice.
+func (c *Cursor) InsertAfter(n Node) {
+	insert(c.slice("InsertAfter"), c.index+1, n)
+	c.skip++
+}
+
+// field returns the field of the parent holding the current node.
+func (c *Cursor) field() reflect.Value {
+	return reflect.Indirect(reflect.ValueOf(c.parent)).FieldByName(c.name)
+}
+
+// slice returns the slice holding the current node for the method op.
+func (c *Cursor) slice(op string) reflect.Value {
+	if c.index < 0 {
+		panic(fmt.Sprintf("ast.Apply: %s of a node in the %s field, which is not a slice", op, c.name))
+	}
+	return c.field()
+}
+
+// insert adds n at index i of the slice v.
+func insert(v reflect.Value, i int, n Node) {
+	v.Set(reflect.Append(v, reflect.Zero(v.Type().Elem())))
+	reflect.Copy(v.Slice(i+1, v.Len()), v.Slice(i, v.Len()))
+	v.Index(i).Set(nodeValue(n, v.Type().Elem()))
+}
+
+// nodeValue returns n as a value to store in a field of type typ.
+func nodeValue(n Node, typ reflect.Type) reflect.Value {
+	if n == nil {
+		return reflect.Zero(typ)
+	}
+	return reflect.ValueOf(n)
+}
+
+type rewriter struct {
+	pre, post ApplyFunc
+}
+
+// visit calls pre and post around the children of the node of c. It
+// returns false once post stopped the traversal.
+func (r *rewriter) visit(c *Cursor) bool {
+	if v := reflect.ValueOf(c.node); v.Kind() == reflect.Ptr && v.IsNil() {
+		c.node = nil
+	}
+	if r.pre != nil && !r.pre(c) {
+		return true
+	}
+	if !r.children(c) {
+		return false
+	}
+	return r.post == nil || r.post(c)
+}
+
+// children visits the children of the node of c.
+func (r *rewriter) children(c *Cursor) bool {
+	switch n := c.node.(type) {
+	case nil:
+		return true
+
+	case Chunk:
+		root := &chunkRoot{n}
+		ok := r.fields(root, "Chunk")
+		c.Replace(root.Chunk)
+		return ok
+
+	// Misc
+	case *Field:
+		return r.fields(n, "Key", "Value")
+	case *ParList:
+		return r.fields(n, "Types", "VarargType")
+	case *FuncName:
+		return r.fields(n, "Func", "Receiver")
+
+	// Expressions
+	case *NilExpr, *TrueExpr, *FalseExpr, *NumberExpr, *StringExpr, *Comma3Expr, *IdentExpr, *BadExpr:
+		return true
+	case *AttrGetExpr:
+		return r.fields(n, "Object", "Key")
+	case *TableExpr:
+		return r.fields(n, "Fields")
+	case *FuncCallExpr:
+		return r.fields(n, "Func", "Receiver", "Args")
+	case *LogicalOpExpr, *RelationalOpExpr, *StringConcatOpExpr, *ArithmeticOpExpr:
+		return r.fields(n, "Lhs", "Rhs")
+	case *UnaryOpExpr:
+		return r.fields(n, "Expr")
+	case *ParenExpr:
+		return r.fields(n, "Expr")
+	case *FunctionExpr:
+		return r.fields(n, "ParList", "ReturnTypes", "Chunk")
+	case *InterpolatedStringExpr:
+		return r.fields(n, "Exprs")
+	case *IfExpr:
+		return r.fields(n, "Condition", "Then", "Else")
+	case *TypeCastExpr:
+		return r.fields(n, "Expr", "Type")
+
+	// Types
+	case *TypeReference:
+		return r.fields(n, "Params")
+	case *SingletonType:
+		return r.fields(n, "Value")
+	case *TableType:
+		return r.fields(n, "Fields", "Array")
+	case *TableTypeField:
+		return r.fields(n, "Key", "Value")
+	case *FunctionType:
+		return r.fields(n, "Params", "Returns")
+	case *UnionType, *IntersectionType:
+		return r.fields(n, "Types")
+	case *OptionalType, *VariadicType:
+		return r.fields(n, "Type")
+	case *TypeofType:
+		return r.fields(n, "Expr")
+
+	// Statements
+	case *AssignStmt:
+		return r.fields(n, "Lhs", "Rhs")
+	case *CompoundAssignStmt:
+		return r.fields(n, "Lhs", "Rhs")
+	case *LocalAssignStmt:
+		return r.fields(n, "Types", "Exprs")
+	case *FuncCallStmt:
+		return r.fields(n, "Expr")
+	case *DoBlockStmt:
+		return r.fields(n, "Chunk")
+	case *WhileStmt:
+		return r.fields(n, "Condition", "Chunk")
+	case *RepeatStmt:
+		return r.fields(n, "Chunk", "Condition")
+	case *IfStmt:
+		return r.fields(n, "Condition", "Then", "Else")
+	case *NumberForStmt:
+		return r.fields(n, "Type", "Init", "Limit", "Step", "Chunk")
+	case *GenericForStmt:
+		return r.fields(n, "Types", "Exprs", "Chunk")
+	case *LocalFunctionStmt:
+		return r.fields(n, "Func")
+	case *FunctionStmt:
+		return r.fields(n, "Name", "Func")
+	case *ReturnStmt:
+		return r.fields(n, "Exprs")
+	case *TypeAliasStmt:
+		return r.fields(n, "Defaults", "Type")
+	case *BreakStmt, *ContinueStmt, *LabelStmt, *GotoStmt, *CommentStmt, *BadStmt, *Comment:
+		return true
+	}
+	panic(fmt.Sprintf("ast.Apply: unexpected node type %T", c.node))
+}
+
+// fields visits the nodes held by the named fields of parent, in order.
+// Slices are read again after each element, which may have changed them.
+func (r *rewriter) fields(parent Node, names ...string) bool {
+	for _, name := range names {
+		c := &Cursor{parent: parent, name: name, index: -1}
+		v := c.field()
+		if v.Kind() != reflect.Slice {
+			c.node, _ = v.Interface().(Node)
+			if !r.visit(c) {
+				return false
+			}
+			continue
+		}
+		for c.index = 0; c.index < c.field().Len(); c.index += 1 + c.skip {
+			c.node, _ = c.field().Index(c.index).Interface().(Node)
+			c.skip = 0
+			if !r.visit(c) {
+				return false
+			}
+		}
+	}
+	return true
+}
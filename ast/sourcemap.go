@@ -0,0 +1,223 @@
+package ast
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SourceMap maps the code written by the printer back to the source it was
+// parsed from. Setting PrintConfig.SourceMap makes Fprint and Format fill
+// it with a mapping for each statement, expression and field name they
+// write whose node has a position.
+type SourceMap struct {
+	// File is the name of the printed code, the chunkname it is loaded
+	// with. It is only used by MarshalJSON and TranslateError.
+	File string
+
+	// Mappings is sorted by the printed position.
+	Mappings []Mapping
+}
+
+// Mapping maps a position in the printed code to the position in the
+// source the printed node comes from. Lines and columns start at 1, columns
+// count bytes like those of Position.
+type Mapping struct {
+	Line   int
+	Column int
+	Source Position
+}
+
+// Lookup returns the source position of the last mapping before or at the
+// printed position line:column, false if there is none on that line.
+func (m *SourceMap) Lookup(line, column int) (Position, bool) {
+	found := false
+	var pos Position
+	for _, mapping := range m.Mappings {
+		if mapping.Line > line || mapping.Line == line && mapping.Column > column {
+			break
+		}
+		if mapping.Line == line {
+			pos, found = mapping.Source, true
+		}
+	}
+	return pos, found
+}
+
+// LookupLine returns the source position of the first mapping on a printed
+// line, false if there is none.
+func (m *SourceMap) LookupLine(line int) (Position, bool) {
+	for _, mapping := range m.Mappings {
+		if mapping.Line == line {
+			return mapping.Source, true
+		}
+	}
+	return Position{}, false
+}
+
+// TranslateError replaces each File:line location in a Lua error message or
+// traceback with the source location it comes from. Locations without a
+// mapping are left as they are.
+func (m *SourceMap) TranslateError(msg string) string {
+	if m.File == "" {
+		return msg
+	}
+	location := regexp.MustCompile(regexp.QuoteMeta(m.File) + `:(\d+)`)
+	return location.ReplaceAllStringFunc(msg, func(match string) string {
+		line, err := strconv.Atoi(match[len(m.File)+1:])
+		if err != nil {
+			return match
+		}
+		pos, ok := m.LookupLine(line)
+		if !ok {
+			return match
+		}
+		return pos.Source + ":" + strconv.Itoa(pos.Line)
+	})
+}
+
+// MarshalJSON encodes the map in the Source Map Revision 3 format.
+func (m *SourceMap) MarshalJSON() ([]byte, error) {
+	sources := []string{}
+	index := map[string]int{}
+	mappings := &strings.Builder{}
+	var column, source, sourceLine, sourceColumn int
+	line, first := 1, true
+	for _, mapping := range m.Mappings {
+		for ; line < mapping.Line; line++ {
+			mappings.WriteByte(';')
+			column, first = 0, true
+		}
+		if !first {
+			mappings.WriteByte(',')
+		}
+		first = false
+		src, ok := index[mapping.Source.Source]
+		if !ok {
+			src = len(sources)
+			index[mapping.Source.Source] = src
+			sources = append(sources, mapping.Source.Source)
+		}
+		vlq(mappings, mapping.Column-1-column)
+		vlq(mappings, src-source)
+		vlq(mappings, mapping.Source.Line-1-sourceLine)
+		vlq(mappings, mapping.Source.Column-1-sourceColumn)
+		column, source = mapping.Column-1, src
+		sourceLine, sourceColumn = mapping.Source.Line-1, mapping.Source.Column-1
+	}
+
+	return json.Marshal(struct {
+		Version  int      `json:"version"`
+		File     string   `json:"file,omitempty"`
+		Sources  []string `json:"sources"`
+		Names    []string `json:"names"`
+		Mappings string   `json:"mappings"`
+	}{3, m.File, sources, []string{}, mappings.String()})
+}
+
+// vlq writes n as a base64 variable length quantity.
+func vlq(b *strings.Builder, n int) {
+	const digits = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+	v := n << 1
+	if n < 0 {
+		v = -n<<1 | 1
+	}
+	for {
+		digit := v & 31
+		v >>= 5
+		if v > 0 {
+			digit |= 32
+		}
+		b.WriteByte(digits[digit])
+		if v == 0 {
+			return
+		}
+	}
+}
+
+// The printer writes a mark before each node it maps, a NUL delimited index
+// in builder.marks. The marks are removed once the code is printed, which
+// is when their position is known. A NUL of the source, which comments and
+// long strings may hold, is written doubled to tell it from a mark.
+const markByte = 0
+
+// mark writes the mark of a node, nothing if there is no source map or the
+// node has no position.
+func (s *builder) mark(node Node) {
+	str := s.markString(node)
+	if s.config.Compact {
+		// Kept until the next byte is written, after a separating space.
+		s.marked += str
+		return
+	}
+	s.Str.WriteString(str)
+}
+
+func (s *builder) markString(node Node) string {
+	holder, ok := node.(PositionHolder)
+	if s.marks == nil || !ok || holder.Pos().Line <= 0 {
+		return ""
+	}
+	*s.marks = append(*s.marks, holder.Pos())
+	return "\x00" + strconv.Itoa(len(*s.marks)-1) + "\x00"
+}
+
+// escapeMarks returns source text to be written with its NULs doubled when
+// marks are written.
+func (s *builder) escapeMarks(str string) string {
+	if s.marks == nil {
+		return str
+	}
+	return strings.ReplaceAll(str, "\x00", "\x00\x00")
+}
+
+// unmark returns str without its marks and their mappings. A NUL that does
+// not start a mark is kept, so that measuring text printed without a source
+// map never fails.
+func unmark(str string, marks []Position) (string, []Mapping) {
+	if strings.IndexByte(str, markByte) < 0 {
+		return str, nil
+	}
+	var mappings []Mapping
+	b := &strings.Builder{}
+	line, column := 1, 1
+	for i := 0; i < len(str); i++ {
+		switch ch := str[i]; ch {
+		case markByte:
+			n := strings.IndexByte(str[i+1:], markByte)
+			if n == 0 {
+				// A doubled NUL of the source.
+				b.WriteByte(ch)
+				column++
+				i++
+				break
+			}
+			var index uint64
+			err := strconv.ErrSyntax
+			if n > 0 {
+				index, err = strconv.ParseUint(str[i+1:i+1+n], 10, 0)
+			}
+			if err != nil || marks != nil && index >= uint64(len(marks)) {
+				b.WriteByte(ch)
+				column++
+				break
+			}
+			if marks != nil {
+				mapping := Mapping{line, column, marks[index]}
+				// Nodes starting at the same place map to the outermost.
+				if n := len(mappings); n == 0 || mappings[n-1].Line != line || mappings[n-1].Column != column {
+					mappings = append(mappings, mapping)
+				}
+			}
+			i += n + 1
+		case '\n':
+			b.WriteByte(ch)
+			line, column = line+1, 1
+		default:
+			b.WriteByte(ch)
+			column++
+		}
+	}
+	return b.String(), mappings
+}
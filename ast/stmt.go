@@ -1,17 +1,38 @@
 package ast
 
 type Stmt interface {
+	Node
 	PositionHolder
+	CommentHolder
 	stmtMarker()
 	String() string
 }
 
 type StmtBase struct {
-	Node
+	NodeBase
+
+	leading  *CommentGroup
+	trailing *CommentGroup
 }
 
 func (stmt *StmtBase) stmtMarker() {}
 
+func (stmt *StmtBase) LeadingComments() *CommentGroup {
+	return stmt.leading
+}
+
+func (stmt *StmtBase) SetLeadingComments(g *CommentGroup) {
+	stmt.leading = g
+}
+
+func (stmt *StmtBase) TrailingComments() *CommentGroup {
+	return stmt.trailing
+}
+
+func (stmt *StmtBase) SetTrailingComments(g *CommentGroup) {
+	stmt.trailing = g
+}
+
 type AssignStmt struct {
 	StmtBase
 
@@ -19,18 +40,25 @@ type AssignStmt struct {
 	Rhs []Expr
 }
 
+// CompoundAssignStmt is an assignment like x += 1, it has a single target.
 type CompoundAssignStmt struct {
 	StmtBase
 
-	Operator string
-	Lhs []Expr
-	Rhs []Expr
+	Operator Op // binary operator applied, OpAdd for +=
+	Lhs      Expr
+	Rhs      Expr
 }
 
 type LocalAssignStmt struct {
 	StmtBase
 
 	Names []string
+	// Attribs holds the Lua 5.4 attribute of each name, "const" or "close",
+	// or "" for the names without one. It is nil when no name has one.
+	Attribs []string
+	// Types holds the Luau type annotation of each name, nil for names
+	// without one. It is nil when no name has one.
+	Types []Type
 	Exprs []Expr
 }
 
@@ -72,6 +100,7 @@ type NumberForStmt struct {
 	StmtBase
 
 	Name  string
+	Type  Type // Luau type annotation of Name, nil if none
 	Init  Expr
 	Limit Expr
 	Step  Expr
@@ -82,6 +111,9 @@ type GenericForStmt struct {
 	StmtBase
 
 	Names []string
+	// Types holds the Luau type annotation of each name, nil for names
+	// without one. It is nil when no name has one.
+	Types []Type
 	Exprs []Expr
 	Chunk Chunk
 }
@@ -124,4 +156,31 @@ type GotoStmt struct {
 	StmtBase
 
 	Label string
-}
\ No newline at end of file
+}
+
+// TypeAliasStmt is a Luau type definition like export type Foo<T> = {T}.
+type TypeAliasStmt struct {
+	StmtBase
+
+	Export   bool
+	Name     string
+	Generics []string // generic names, packs end with "..."
+	// Defaults holds the default type of each generic name, nil for names
+	// without one. It is nil when no name has one.
+	Defaults []Type
+	Type     Type
+}
+
+// CommentStmt holds comments that are not attached to any statement, like
+// the ones at the end of a block or in a file without code.
+type CommentStmt struct {
+	StmtBase
+
+	Comments *CommentGroup
+}
+
+// BadStmt is a placeholder for a statement containing syntax errors, it is
+// only produced when parsing with error recovery.
+type BadStmt struct {
+	StmtBase
+}
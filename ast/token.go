@@ -4,18 +4,26 @@ import (
 	"fmt"
 )
 
+// Position is a location in a source file. Line and Column start at 1,
+// Offset is the byte offset from the start of the source, starting at 0.
 type Position struct {
 	Source string
 	Line   int
 	Column int
+	Offset int
 }
 
 type Token struct {
-	Type int
-	Name string
-	Str  string
-	Num  float64
-	Pos  Position
+	Type  int
+	Name  string
+	Str   string
+	Num   float64
+	Int   int64 // value of integer numbers
+	IsInt bool  // whether a number is an integer
+	Quote byte  // quote of strings, '[' for long brackets
+	Level int   // level of long bracket strings
+	Pos   Position
+	End   Position // position immediately after the token
 }
 
 func (t *Token) String() string {
@@ -0,0 +1,97 @@
+package ast
+
+// Type is a Luau type annotation.
+type Type interface {
+	Node
+	PositionHolder
+	typeMarker()
+	String() string
+}
+
+type TypeBase struct {
+	NodeBase
+}
+
+func (t *TypeBase) typeMarker() {}
+
+// TypeReference is a named type like number, Foo<T> or module.Foo.
+type TypeReference struct {
+	TypeBase
+
+	Prefix string // module of the type, "" if none
+	Name   string // generic packs end with "..."
+	Params []Type // generic parameters, nil if none
+}
+
+// SingletonType is a type with a single value, Value is a *StringExpr, a
+// *TrueExpr or a *FalseExpr.
+type SingletonType struct {
+	TypeBase
+
+	Value ConstExpr
+}
+
+// TableType is a table type like {x: number, [string]: boolean}, or
+// {number} in which case only Array is set.
+type TableType struct {
+	TypeBase
+
+	Fields []*TableTypeField
+	Array  Type
+}
+
+// TableTypeField is a property of a TableType, either named or an indexer
+// like [string]: boolean in which case Key is set.
+type TableTypeField struct {
+	NodeBase
+
+	Name  string
+	Key   Type
+	Value Type
+}
+
+// FunctionType is a function type like <T>(a: T, ...number) -> string.
+type FunctionType struct {
+	TypeBase
+
+	Generics   []string // generic names, packs end with "..."
+	Params     []Type
+	ParamNames []string // name of each parameter or "", nil if none has one
+	Returns    []Type
+}
+
+// UnionType is a type like A | B.
+type UnionType struct {
+	TypeBase
+
+	Types []Type
+}
+
+// IntersectionType is a type like A & B.
+type IntersectionType struct {
+	TypeBase
+
+	Types []Type
+}
+
+// OptionalType is a type like T?, a shorthand for T | nil.
+type OptionalType struct {
+	TypeBase
+
+	Type Type
+}
+
+// TypeofType is the type of an expression, typeof(expr).
+type TypeofType struct {
+	TypeBase
+
+	Expr Expr
+}
+
+// VariadicType is a type like ...T, it is only found at the end of the
+// parameters and return types of functions and of generic parameters.
+type VariadicType struct {
+	TypeBase
+
+	Type Type
+}
@@ -0,0 +1,253 @@
+package ast
+
+import "fmt"
+
+// Visitor is called by Walk on the nodes of a tree. The Visitor returned
+// by Visit walks the children of node, they are skipped when it is nil.
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+func walkExprList(v Visitor, list []Expr) {
+	for _, x := range list {
+		Walk(v, x)
+	}
+}
+
+func walkTypeList(v Visitor, list []Type) {
+	for _, t := range list {
+		if t != nil {
+			Walk(v, t)
+		}
+	}
+}
+
+func walkChunk(v Visitor, c Chunk) {
+	if c != nil {
+		Walk(v, c)
+	}
+}
+
+// Walk calls v.Visit on node, which must not be nil, then walks its
+// children in source order with the Visitor it returned, and calls Visit(nil)
+// on that Visitor once they are done. Missing children, like the step of a
+// for loop or a type annotation, are not walked.
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case Chunk:
+		for _, s := range n {
+			Walk(v, s)
+		}
+
+	// Misc
+	case *Field:
+		if n.Key != nil {
+			Walk(v, n.Key)
+		}
+		Walk(v, n.Value)
+
+	case *ParList:
+		walkTypeList(v, n.Types)
+		if n.VarargType != nil {
+			Walk(v, n.VarargType)
+		}
+
+	case *FuncName:
+		if n.Func != nil {
+			Walk(v, n.Func)
+		} else {
+			Walk(v, n.Receiver)
+		}
+
+	// Expressions
+	case *NilExpr, *TrueExpr, *FalseExpr, *NumberExpr, *StringExpr, *Comma3Expr, *IdentExpr, *BadExpr:
+		// nothing to do
+
+	case *AttrGetExpr:
+		Walk(v, n.Object)
+		Walk(v, n.Key)
+
+	case *TableExpr:
+		for _, f := range n.Fields {
+			Walk(v, f)
+		}
+
+	case *FuncCallExpr:
+		if n.Func != nil {
+			Walk(v, n.Func)
+		} else {
+			Walk(v, n.Receiver)
+		}
+		walkExprList(v, n.Args)
+
+	case *LogicalOpExpr:
+		Walk(v, n.Lhs)
+		Walk(v, n.Rhs)
+
+	case *RelationalOpExpr:
+		Walk(v, n.Lhs)
+		Walk(v, n.Rhs)
+
+	case *StringConcatOpExpr:
+		Walk(v, n.Lhs)
+		Walk(v, n.Rhs)
+
+	case *ArithmeticOpExpr:
+		Walk(v, n.Lhs)
+		Walk(v, n.Rhs)
+
+	case *UnaryOpExpr:
+		Walk(v, n.Expr)
+
+	case *ParenExpr:
+		Walk(v, n.Expr)
+
+	case *FunctionExpr:
+		if n.ParList != nil {
+			Walk(v, n.ParList)
+		}
+		walkTypeList(v, n.ReturnTypes)
+		walkChunk(v, n.Chunk)
+
+	case *InterpolatedStringExpr:
+		walkExprList(v, n.Exprs)
+
+	case *IfExpr:
+		Walk(v, n.Condition)
+		Walk(v, n.Then)
+		Walk(v, n.Else)
+
+	case *TypeCastExpr:
+		Walk(v, n.Expr)
+		Walk(v, n.Type)
+
+	// Types
+	case *TypeReference:
+		walkTypeList(v, n.Params)
+
+	case *SingletonType:
+		Walk(v, n.Value)
+
+	case *TableType:
+		for _, f := range n.Fields {
+			Walk(v, f)
+		}
+		if n.Array != nil {
+			Walk(v, n.Array)
+		}
+
+	case *TableTypeField:
+		if n.Key != nil {
+			Walk(v, n.Key)
+		}
+		Walk(v, n.Value)
+
+	case *FunctionType:
+		walkTypeList(v, n.Params)
+		walkTypeList(v, n.Returns)
+
+	case *UnionType:
+		walkTypeList(v, n.Types)
+
+	case *IntersectionType:
+		walkTypeList(v, n.Types)
+
+	case *OptionalType:
+		Walk(v, n.Type)
+
+	case *TypeofType:
+		Walk(v, n.Expr)
+
+	case *VariadicType:
+		Walk(v, n.Type)
+
+	// Statements
+	case *AssignStmt:
+		walkExprList(v, n.Lhs)
+		walkExprList(v, n.Rhs)
+
+	case *CompoundAssignStmt:
+		Walk(v, n.Lhs)
+		Walk(v, n.Rhs)
+
+	case *LocalAssignStmt:
+		walkTypeList(v, n.Types)
+		walkExprList(v, n.Exprs)
+
+	case *FuncCallStmt:
+		Walk(v, n.Expr)
+
+	case *DoBlockStmt:
+		walkChunk(v, n.Chunk)
+
+	case *WhileStmt:
+		Walk(v, n.Condition)
+		walkChunk(v, n.Chunk)
+
+	case *RepeatStmt:
+		walkChunk(v, n.Chunk)
+		Walk(v, n.Condition)
+
+	case *IfStmt:
+		Walk(v, n.Condition)
+		walkChunk(v, n.Then)
+		walkChunk(v, n.Else)
+
+	case *NumberForStmt:
+		if n.Type != nil {
+			Walk(v, n.Type)
+		}
+		Walk(v, n.Init)
+		Walk(v, n.Limit)
+		if n.Step != nil {
+			Walk(v, n.Step)
+		}
+		walkChunk(v, n.Chunk)
+
+	case *GenericForStmt:
+		walkTypeList(v, n.Types)
+		walkExprList(v, n.Exprs)
+		walkChunk(v, n.Chunk)
+
+	case *LocalFunctionStmt:
+		Walk(v, n.Func)
+
+	case *FunctionStmt:
+		Walk(v, n.Name)
+		Walk(v, n.Func)
+
+	case *ReturnStmt:
+		walkExprList(v, n.Exprs)
+
+	case *TypeAliasStmt:
+		walkTypeList(v, n.Defaults)
+		Walk(v, n.Type)
+
+	case *BreakStmt, *ContinueStmt, *LabelStmt, *GotoStmt, *CommentStmt, *BadStmt, *Comment:
+		// nothing to do
+
+	default:
+		panic(fmt.Sprintf("ast.Walk: unexpected node type %T", n))
+	}
+
+	v.Visit(nil)
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect is Walk with a function for Visitor: f is called on node, and on
+// its children only if it returns true, then with nil after them.
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
@@ -0,0 +1,314 @@
+// Package cst holds a lossless concrete syntax tree of Lua source: every
+// token along with the whitespace and comments around it, so that the
+// source can be edited a token at a time and written back with the rest
+// of it untouched.
+package cst
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+
+	"github.com/notnoobmaster/luautil/ast"
+	"github.com/notnoobmaster/luautil/parse"
+)
+
+// TriviaKind tells what a Trivia is.
+type TriviaKind int
+
+const (
+	Whitespace TriviaKind = iota
+	Comment
+)
+
+// Trivia is whitespace or a comment between two tokens.
+type Trivia struct {
+	Kind TriviaKind
+	Text string
+}
+
+// Token is a token of the source and the trivia around it. The trivia
+// between two tokens up to the end of the line of the first one is the
+// Trailing trivia of the first, the rest is the Leading trivia of the
+// second.
+type Token struct {
+	Type     int // token type, like parse.TIdent or '('
+	Leading  []Trivia
+	Text     string // the token as written
+	Trailing []Trivia
+
+	// Pos and End are the position of Text in the source, they are not
+	// updated by edits.
+	Pos ast.Position
+	End ast.Position
+}
+
+// Node is a node of the syntax tree along with the tokens it covers,
+// Tokens[First:Last] of the File. Parentheses belong to the node of the
+// ast.ParenExpr around the expression.
+type Node struct {
+	AST         ast.Node
+	First, Last int
+	Parent      *Node
+	Children    []*Node
+}
+
+// File is a parsed source file.
+type File struct {
+	// Tokens holds every token of the source, the last one is the end of
+	// the file which has no text.
+	Tokens []*Token
+
+	// Root is the node of the whole chunk.
+	Root *Node
+
+	name    string
+	options parse.Options
+}
+
+// Parse reads the tokens of src and parses them into a tree.
+func Parse(src []byte, name string, opts parse.Options) (*File, error) {
+	chunk, err := parse.ParseWithOptions(bytes.NewReader(src), name, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &File{name: name, options: opts}
+	// The trivia is read from the gaps between the tokens.
+	tokenizer := parse.NewTokenizer(bytes.NewReader(src), name, parse.TokenizerOptions{Dialect: opts.Dialect})
+	prev := 0
+	for {
+		tok, err := tokenizer.Next()
+		if err != nil {
+			return nil, err
+		}
+		start, end := tok.Pos.Offset, tok.End.Offset
+		if tok.Type == parse.EOF {
+			start, end = len(src), len(src)
+		}
+		token := &Token{Type: tok.Type, Text: string(src[start:end]), Pos: tok.Pos, End: tok.End}
+		gap := string(src[prev:start])
+		if n := len(f.Tokens); n > 0 {
+			last := f.Tokens[n-1]
+			last.Trailing, token.Leading = splitTrivia(trivia(gap))
+		} else {
+			token.Leading = trivia(gap)
+		}
+		f.Tokens = append(f.Tokens, token)
+		prev = end
+		if tok.Type == parse.EOF {
+			break
+		}
+	}
+
+	f.Root = &Node{AST: chunk, Last: len(f.Tokens)}
+	f.build(f.Root, chunk)
+	return f, nil
+}
+
+// build adds the nodes below n in the tree of node.
+func (f *File) build(n *Node, node ast.Node) {
+	root := true
+	ast.Inspect(node, func(child ast.Node) bool {
+		if root {
+			root = false
+			return true
+		}
+		holder, ok := child.(ast.PositionHolder)
+		if !ok || holder.Pos().Line <= 0 {
+			return true
+		}
+		c := &Node{
+			AST:    child,
+			First:  f.tokenAt(holder.Pos().Offset),
+			Last:   f.tokenAt(holder.End().Offset),
+			Parent: n,
+		}
+		n.Children = append(n.Children, c)
+		f.build(c, child)
+		return false
+	})
+}
+
+// tokenAt returns the index of the first token starting at or after
+// offset.
+func (f *File) tokenAt(offset int) int {
+	return sort.Search(len(f.Tokens)-1, func(i int) bool {
+		return f.Tokens[i].Pos.Offset >= offset
+	})
+}
+
+// Bytes returns the source, with the edits made to the tokens.
+func (f *File) Bytes() []byte {
+	return []byte(f.String())
+}
+
+func (f *File) String() string {
+	b := &strings.Builder{}
+	for _, tok := range f.Tokens {
+		tok.write(b)
+	}
+	return b.String()
+}
+
+func (tok *Token) write(b *strings.Builder) {
+	for _, t := range tok.Leading {
+		b.WriteString(t.Text)
+	}
+	b.WriteString(tok.Text)
+	for _, t := range tok.Trailing {
+		b.WriteString(t.Text)
+	}
+}
+
+// Text returns the source of a node, without the trivia before its first
+// token and after its last.
+func (f *File) Text(n *Node) string {
+	b := &strings.Builder{}
+	for i := n.First; i < n.Last; i++ {
+		tok := f.Tokens[i]
+		if i > n.First {
+			for _, t := range tok.Leading {
+				b.WriteString(t.Text)
+			}
+		}
+		b.WriteString(tok.Text)
+		if i < n.Last-1 {
+			for _, t := range tok.Trailing {
+				b.WriteString(t.Text)
+			}
+		}
+	}
+	return b.String()
+}
+
+// Replace replaces the tokens of a node with text, keeping the trivia
+// before its first token and after its last.
+func (f *File) Replace(n *Node, text string) {
+	if n.First == n.Last {
+		return
+	}
+	first, last := f.Tokens[n.First], f.Tokens[n.Last-1]
+	first.Text = text
+	first.Trailing = last.Trailing
+	for _, tok := range f.Tokens[n.First+1 : n.Last] {
+		tok.Leading, tok.Text, tok.Trailing = nil, "", nil
+	}
+}
+
+// Find returns the node of an ast node of the tree, nil if there is none.
+// Blocks other than the whole chunk have no node.
+func (f *File) Find(node ast.Node) *Node {
+	if chunk, ok := node.(ast.Chunk); ok {
+		if root := f.Root.AST.(ast.Chunk); len(chunk) == len(root) && (len(chunk) == 0 || &chunk[0] == &root[0]) {
+			return f.Root
+		}
+		return nil
+	}
+	var found *Node
+	var find func(n *Node)
+	find = func(n *Node) {
+		for _, c := range n.Children {
+			if found != nil {
+				return
+			}
+			if c.AST == node {
+				found = c
+				return
+			}
+			find(c)
+		}
+	}
+	find(f.Root)
+	return found
+}
+
+// NodeAt returns the innermost node covering the token at index i.
+func (f *File) NodeAt(i int) *Node {
+	n := f.Root
+	for {
+		next := (*Node)(nil)
+		for _, c := range n.Children {
+			if c.First <= i && i < c.Last {
+				next = c
+				break
+			}
+		}
+		if next == nil {
+			return n
+		}
+		n = next
+	}
+}
+
+// AST parses the source with its edits, it returns the tree of the
+// unmodified source when nothing was edited.
+func (f *File) AST() (ast.Chunk, error) {
+	return parse.ParseWithOptions(strings.NewReader(f.String()), f.name, f.options)
+}
+
+// trivia splits the text between two tokens into whitespace and comments.
+func trivia(gap string) []Trivia {
+	var list []Trivia
+	for gap != "" {
+		i := strings.Index(gap, "--")
+		if i < 0 {
+			i = len(gap)
+		}
+		if i > 0 {
+			list = append(list, Trivia{Whitespace, gap[:i]})
+			gap = gap[i:]
+			continue
+		}
+		n := commentLength(gap)
+		list = append(list, Trivia{Comment, gap[:n]})
+		gap = gap[n:]
+	}
+	return list
+}
+
+// commentLength returns the length of the comment starting text.
+func commentLength(text string) int {
+	if level, ok := longBracket(text[2:]); ok {
+		close := "]" + strings.Repeat("=", level) + "]"
+		if i := strings.Index(text, close); i >= 0 {
+			return i + len(close)
+		}
+		return len(text)
+	}
+	if i := strings.IndexAny(text, "\r\n"); i >= 0 {
+		return i
+	}
+	return len(text)
+}
+
+// longBracket reports whether text starts with an opening long bracket and
+// returns its level.
+func longBracket(text string) (int, bool) {
+	if !strings.HasPrefix(text, "[") {
+		return 0, false
+	}
+	level := 0
+	for level+1 < len(text) && text[level+1] == '=' {
+		level++
+	}
+	return level, level+1 < len(text) && text[level+1] == '['
+}
+
+// splitTrivia splits the trivia after a token at the end of its line.
+func splitTrivia(list []Trivia) (trailing, leading []Trivia) {
+	for i, t := range list {
+		if t.Kind != Whitespace {
+			continue
+		}
+		if j := strings.IndexByte(t.Text, '\n'); j >= 0 {
+			trailing = append(list[:i:i], Trivia{Whitespace, t.Text[:j+1]})
+			leading = list[i+1:]
+			if rest := t.Text[j+1:]; rest != "" {
+				leading = append([]Trivia{{Whitespace, rest}}, leading...)
+			}
+			return trailing, leading
+		}
+	}
+	return list, nil
+}
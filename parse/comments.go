@@ -0,0 +1,115 @@
+package parse
+
+import (
+	"github.com/notnoobmaster/luautil/ast"
+)
+
+// comment is a comment read by the scanner that is not attached to a
+// statement yet.
+//
+// Comments are matched to statements through their offsets: the pending
+// comments before a statement belong to it or to the statement before it,
+// once it is parsed the ones before its end that no nested statement took
+// belong to it too, and once a block is closed every pending comment
+// before the closing token belongs to that block.
+type comment struct {
+	*ast.Comment
+	trailing bool // whether a token precedes the comment on its line
+}
+
+// takeComments moves the comments skipped by the scanner to the pending
+// comments, it is called after every token scanned.
+func (p *parser) takeComments() {
+	for _, c := range p.scanner.comments {
+		p.all = append(p.all, c)
+		p.comments = append(p.comments, &comment{
+			Comment:  c,
+			trailing: p.lastLine == c.Pos().Line,
+		})
+	}
+	p.scanner.comments = p.scanner.comments[:0]
+}
+
+// take removes and returns the pending comments for which f returns true.
+func (p *parser) take(f func(c *comment) bool) (taken []*ast.Comment) {
+	rest := p.comments[:0]
+	for _, c := range p.comments {
+		if f(c) {
+			taken = append(taken, c.Comment)
+		} else {
+			rest = append(rest, c)
+		}
+	}
+	p.comments = rest
+	return
+}
+
+func appendComments(g *ast.CommentGroup, list []*ast.Comment) *ast.CommentGroup {
+	if len(list) == 0 {
+		return g
+	}
+	if g == nil {
+		g = &ast.CommentGroup{}
+	}
+	g.List = append(g.List, list...)
+	return g
+}
+
+// lead is called before the next statement of chunk is parsed, so that
+// nested blocks do not take the comments before it. Comments continuing the
+// line of the previous statement become its trailing comments, the others
+// are returned to lead the next statement.
+func (p *parser) lead(chunk ast.Chunk) []*ast.Comment {
+	start := p.tok.Pos.Offset
+	if len(chunk) > 0 {
+		prev := chunk[len(chunk)-1]
+		trailing := p.take(func(c *comment) bool {
+			return c.trailing && c.Pos().Offset < start
+		})
+		prev.SetTrailingComments(appendComments(prev.TrailingComments(), trailing))
+	}
+	return p.take(func(c *comment) bool {
+		return c.Pos().Offset < start
+	})
+}
+
+// attach is called once stmt has been parsed with the leading comments
+// returned by lead. Comments inside of it that no nested statement took are
+// added to its leading comments as well.
+func (p *parser) attach(stmt ast.Stmt, leading []*ast.Comment) {
+	end := stmt.End().Offset
+	inside := p.take(func(c *comment) bool {
+		return c.Pos().Offset < end
+	})
+	stmt.SetLeadingComments(appendComments(stmt.LeadingComments(), append(leading, inside...)))
+}
+
+// closeBlock is called when the parser reaches the end of chunk. Comments
+// right before the token ending it are added to the last statement if they
+// continue its line, all others are kept in a CommentStmt at the end of the
+// chunk.
+func (p *parser) closeBlock(chunk ast.Chunk) ast.Chunk {
+	var last ast.Stmt
+	if len(chunk) > 0 {
+		last = chunk[len(chunk)-1]
+	}
+	end := p.tok.Pos.Offset
+
+	trailing := p.take(func(c *comment) bool {
+		return last != nil && c.trailing && c.Pos().Offset < end
+	})
+	if last != nil {
+		last.SetTrailingComments(appendComments(last.TrailingComments(), trailing))
+	}
+
+	dangling := p.take(func(c *comment) bool {
+		return c.Pos().Offset < end
+	})
+	if len(dangling) > 0 {
+		stmt := &ast.CommentStmt{Comments: appendComments(nil, dangling)}
+		stmt.SetPos(dangling[0].Pos())
+		stmt.SetEnd(dangling[len(dangling)-1].End())
+		chunk = append(chunk, stmt)
+	}
+	return chunk
+}
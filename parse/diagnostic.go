@@ -0,0 +1,91 @@
+package parse
+
+import (
+	"github.com/notnoobmaster/luautil/ast"
+)
+
+// Severity tells how serious a Diagnostic is.
+type Severity int
+
+const (
+	// SeverityError is a problem that keeps the code from being parsed.
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityInfo
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	case SeverityInfo:
+		return "info"
+	}
+	return "unknown"
+}
+
+// Codes of diagnostics, they tell what is wrong independently of the
+// message.
+const (
+	CodeSyntax      = "syntax"      // the tokens do not form valid code
+	CodeToken       = "token"       // a malformed token, like an unterminated string
+	CodeUnsupported = "unsupported" // a feature the dialect does not have
+	CodeUnclosed    = "unclosed"    // a block or a bracket that is not closed
+	CodeAmbiguous   = "ambiguous"   // a call that may be meant as a new statement
+	CodeConst       = "const"       // an assignment to a <const> variable
+	CodeLimit       = "limit"       // the input exceeds a limit of Options
+)
+
+// Diagnostic is a problem found in the source.
+type Diagnostic struct {
+	Severity Severity
+	Code     string
+	Pos      ast.Position // start of the offending token
+	End      ast.Position // position immediately after it
+	Token    string       // the offending token, as written
+	Message  string
+	Fixes    []Fix // suggested fixes, if any
+}
+
+// Fix is an edit of the source suggested to fix a Diagnostic: the text from
+// Pos to End is replaced with NewText, it is inserted when they are equal.
+type Fix struct {
+	Message string
+	Pos     ast.Position
+	End     ast.Position
+	NewText string
+}
+
+// asError returns err as an *Error, errors of another type are reported at
+// tok.
+func asError(err error, tok ast.Token) *Error {
+	if e, ok := err.(*Error); ok {
+		return e
+	}
+	return &Error{Diagnostic: Diagnostic{Code: CodeToken, Pos: tok.Pos, End: tok.End, Token: tok.Str, Message: err.Error()}}
+}
+
+// Diagnostics returns the diagnostics of an error returned by the parser,
+// nil if it is not a parse error.
+func Diagnostics(err error) []Diagnostic {
+	switch err := err.(type) {
+	case *Error:
+		return []Diagnostic{err.Diagnostic}
+	case ErrorList:
+		list := make([]Diagnostic, len(err))
+		for i, e := range err {
+			list[i] = e.Diagnostic
+		}
+		return list
+	case *LimitError:
+		return []Diagnostic{{
+			Code:    CodeLimit,
+			Pos:     err.Pos,
+			End:     err.Pos,
+			Message: err.Limit + " exceeded",
+		}}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package parse
+
+// Dialect is a version of Lua the parser accepts.
+type Dialect int
+
+const (
+	// AnyDialect accepts every feature of every other dialect at once.
+	AnyDialect Dialect = iota
+	Lua51
+	Lua52
+	Lua53
+	Lua54
+	Luau
+)
+
+func (d Dialect) String() string {
+	switch d {
+	case Lua51:
+		return "Lua 5.1"
+	case Lua52:
+		return "Lua 5.2"
+	case Lua53:
+		return "Lua 5.3"
+	case Lua54:
+		return "Lua 5.4"
+	case Luau:
+		return "Luau"
+	}
+	return "any dialect"
+}
+
+// Options configure the parser.
+type Options struct {
+	Dialect Dialect
+
+	// Limits of the input, exceeding one of them makes ParseContext and
+	// ParseWithOptions fail with a *LimitError. MaxBytes is the size of the
+	// input and MaxTokens is the number of its tokens, comments excluded,
+	// they are not checked when 0. MaxDepth is how deeply statements,
+	// expressions and types may be nested, which bounds the recursion of
+	// the parser: it is DefaultMaxDepth when 0, and not checked when
+	// negative.
+	MaxBytes  int
+	MaxDepth  int
+	MaxTokens int
+}
+
+// DefaultMaxDepth is the nesting depth allowed when Options.MaxDepth is 0,
+// the recursion limit of Luau. It keeps deeply nested input from
+// exhausting the stack.
+const DefaultMaxDepth = 1000
+
+// feature is a set of language features that are not part of every
+// dialect.
+type feature uint
+
+const (
+	featGoto            feature = 1 << iota // goto statements and labels
+	featContinue                            // continue statements
+	featCompound                            // compound assignments like x += 1
+	featCompoundBitwise                     // the &=, |=, <<= and >>= compound assignments
+	featFloorDiv                            // the // operator
+	featBitwise                             // the &, |, ~, << and >> operators
+	featEscapeHex                           // \x and \z escapes in strings
+	featEscapeUTF8                          // \u{...} escapes in strings
+	featEscapeUTF8Long                      // \u{...} escapes past Unicode, up to 2^31-1
+	featAmbiguousCall                       // rejecting a ( that starts a line as ambiguous
+	featBinary                              // 0b binary numbers
+	featOctal                               // 0o octal numbers
+	featDigitSep                            // _ between the digits of numbers
+	featHexFloat                            // hexadecimal floats like 0x1.8p3
+	featAttribs                             // <const> and <close> local attributes
+	featTypes                               // Luau type annotations, aliases and casts
+	featIfExpr                              // Luau if-then-else expressions
+	featInterp                              // Luau `interpolated {strings}`
+
+	featAll feature = 1<<iota - 1
+)
+
+var dialectFeatures = [...]feature{
+	AnyDialect: featAll,
+	Lua51:      featAmbiguousCall,
+	Lua52:      featGoto | featEscapeHex | featHexFloat,
+	Lua53:      featGoto | featEscapeHex | featHexFloat | featEscapeUTF8 | featFloorDiv | featBitwise,
+	Lua54:      featGoto | featEscapeHex | featHexFloat | featEscapeUTF8 | featEscapeUTF8Long | featFloorDiv | featBitwise | featAttribs,
+	Luau: featAmbiguousCall | featContinue | featCompound | featFloorDiv | featEscapeHex | featEscapeUTF8 |
+		featBinary | featDigitSep | featTypes | featIfExpr | featInterp,
+}
+
+func (d Dialect) has(f feature) bool {
+	if d < 0 || int(d) >= len(dialectFeatures) {
+		d = AnyDialect
+	}
+	return dialectFeatures[d]&f == f
+}
@@ -0,0 +1,22 @@
+package parse
+
+import (
+	"bytes"
+
+	"github.com/notnoobmaster/luautil/ast"
+)
+
+// FormatRange parses src and reformats the lines from startLine to endLine
+// with ast.FormatRange.
+func FormatRange(src []byte, startLine, endLine int, config *ast.PrintConfig) ([]byte, error) {
+	return FormatRangeWithOptions(src, startLine, endLine, Options{}, config)
+}
+
+// FormatRangeWithOptions is like FormatRange but parses src with opts.
+func FormatRangeWithOptions(src []byte, startLine, endLine int, opts Options, config *ast.PrintConfig) ([]byte, error) {
+	chunk, err := ParseWithOptions(bytes.NewReader(src), "", opts)
+	if err != nil {
+		return nil, err
+	}
+	return ast.FormatRange(src, chunk, startLine, endLine, config)
+}
@@ -3,34 +3,61 @@ package parse
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"reflect"
 	"strconv"
 	"strings"
+	"unicode"
 
 	"github.com/notnoobmaster/luautil/ast"
 )
 
 const EOF = -1
-const whitespace1 = 1<<'\t' | 1<<' '
 const whitespace2 = 1<<'\t' | 1<<'\n' | 1<<'\r' | 1<<' '
 
+// Error is a syntax error, the Diagnostic of the error holds its position,
+// message and offending token.
 type Error struct {
-	Pos     ast.Position
-	Message string
-	Token   string
+	Diagnostic
+
+	eof bool // whether the error is at the end of the input
 }
 
 func (e *Error) Error() string {
 	pos := e.Pos
-	if pos.Line == EOF {
-		return fmt.Sprintf("%v at EOF:   %s\n", pos.Source, e.Message)
+	if e.eof || pos.Line == EOF {
+		return fmt.Sprintf("%v line:%d(column:%d) at EOF:   %s\n", pos.Source, pos.Line, pos.Column, e.Message)
 	} else {
 		return fmt.Sprintf("%v line:%d(column:%d) near '%v':   %s\n", pos.Source, pos.Line, pos.Column, e.Token, e.Message)
 	}
 }
 
+// ErrorList holds the syntax errors ParseAll went past, in the order the
+// parser ran into them.
+type ErrorList []*Error
+
+// Error describes the first error and counts the others.
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+	return fmt.Sprintf("%s(and %d more errors)", l[0], len(l)-1)
+}
+
+// Err returns l as the error of a parse, which is nil when no error was
+// found rather than an empty ErrorList.
+func (l ErrorList) Err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return l
+}
+
 func writeChar(buf *bytes.Buffer, c int) { buf.WriteByte(byte(c)) }
 
 func isDecimal(ch int) bool { return '0' <= ch && ch <= '9' }
@@ -48,8 +75,21 @@ func isIdent(ch int, pos int) bool {
 }
 
 type Scanner struct {
-	Pos    ast.Position
+	Pos    ast.Position // position of the last character read
 	reader *bufio.Reader
+	offset int           // number of bytes read
+	rec    *bytes.Buffer // receives every character read by Next when set
+	buf    bytes.Buffer  // text of the token being scanned
+	raw    bytes.Buffer  // source text of the number being scanned
+
+	dialect Dialect // dialect whose tokens are accepted
+	interp  []int   // braces open in the expression of each interpolated string being scanned
+
+	keepComments bool           // whether Scan collects the comments it skips
+	comments     []*ast.Comment // comments skipped by Scan, for their reader to drain
+
+	start ast.Position // start of the token being scanned
+	eof   ast.Position // end of the input, once it is reached
 }
 
 func NewScanner(reader io.Reader, source string) *Scanner {
@@ -63,9 +103,42 @@ func NewScanner(reader io.Reader, source string) *Scanner {
 	}
 }
 
-func (sc *Scanner) Error(tok string, msg string) *Error { return &Error{sc.Pos, msg, tok} }
+// Error returns an error about the malformed token being scanned, which
+// ends at the last character read.
+func (sc *Scanner) Error(tok string, msg string) *Error {
+	end, eof := sc.end(), sc.Pos.Line == EOF
+	if eof {
+		end = sc.eof
+	}
+	err := &Error{Diagnostic{Code: CodeToken, Pos: sc.start, End: end, Token: tok, Message: msg}, eof}
+	if err.Pos.Line == EOF {
+		err.Pos = sc.eof
+	}
+	return err
+}
+
+// TokenError returns a syntax error at tok.
+func (sc *Scanner) TokenError(tok ast.Token, msg string) *Error {
+	if tok.Type == EOF {
+		return &Error{Diagnostic{Code: CodeSyntax, Pos: sc.eof, End: sc.eof, Message: msg}, true}
+	}
+	return &Error{Diagnostic: Diagnostic{Code: CodeSyntax, Pos: tok.Pos, End: tok.End, Token: tok.Str, Message: msg}}
+}
+
+// tokenPos returns the position of tok, the end of the input for EOF.
+func (sc *Scanner) tokenPos(tok ast.Token) ast.Position {
+	if tok.Type == EOF {
+		return sc.eof
+	}
+	return tok.Pos
+}
 
-func (sc *Scanner) TokenError(tok ast.Token, msg string) *Error { return &Error{tok.Pos, msg, tok.Str} }
+// unsupported returns an error about a token the dialect does not have.
+func (sc *Scanner) unsupported(tok string, msg string) *Error {
+	err := sc.Error(tok, msg)
+	err.Code = CodeUnsupported
+	return err
+}
 
 func (sc *Scanner) readNext() int {
 	ch, err := sc.reader.ReadByte()
@@ -84,24 +157,47 @@ func (sc *Scanner) Newline(ch int) {
 	next := sc.Peek()
 	if ch == '\n' && next == '\r' || ch == '\r' && next == '\n' {
 		sc.reader.ReadByte()
+		sc.offset++
 	}
 }
 
 func (sc *Scanner) Next() int {
 	ch := sc.readNext()
+	if ch != EOF {
+		sc.offset++
+	}
 	switch ch {
 	case '\n', '\r':
 		sc.Newline(ch)
 		ch = int('\n')
 	case EOF:
+		if sc.Pos.Line != EOF {
+			sc.eof = ast.Position{Source: sc.Pos.Source, Line: sc.Pos.Line, Column: sc.Pos.Column + 1, Offset: sc.offset}
+		}
 		sc.Pos.Line = EOF
 		sc.Pos.Column = 0
+		sc.Pos.Offset = sc.offset
+		return ch
 	default:
 		sc.Pos.Column++
 	}
+	sc.Pos.Offset = sc.offset - 1
+	if sc.rec != nil && ch != EOF {
+		writeChar(sc.rec, ch)
+	}
 	return ch
 }
 
+// end returns the position immediately after the last character read.
+func (sc *Scanner) end() ast.Position {
+	pos := sc.Pos
+	if pos.Line != EOF {
+		pos.Column++
+		pos.Offset++
+	}
+	return pos
+}
+
 func (sc *Scanner) Peek() int {
 	ch := sc.readNext()
 	if ch != EOF {
@@ -117,23 +213,26 @@ func (sc *Scanner) skipWhiteSpace(whitespace int64) int {
 	return ch
 }
 
-func (sc *Scanner) skipComments(ch int) error {
+// scanComment reads a comment whose leading dashes have already been consumed
+// and writes its source text, dashes included, to buf.
+func (sc *Scanner) scanComment(buf *bytes.Buffer) error {
+	buf.WriteString("--")
+	sc.rec = buf
+	defer func() { sc.rec = nil }()
+
 	// multiline comment
 	if sc.Peek() == '[' {
-		ch = sc.Next()
+		sc.Next()
 		if sc.Peek() == '[' || sc.Peek() == '=' {
-			var buf bytes.Buffer
-			if err := sc.scanMultilineString(sc.Next(), &buf); err != nil {
+			var body bytes.Buffer
+			if _, err := sc.scanMultilineString(sc.Next(), &body); err != nil {
 				return sc.Error(buf.String(), "invalid multiline comment")
 			}
 			return nil
 		}
 	}
-	for {
-		if ch == '\n' || ch == '\r' || ch < 0 {
-			break
-		}
-		ch = sc.Next()
+	for ch := sc.Peek(); ch != '\n' && ch != '\r' && ch != EOF; ch = sc.Peek() {
+		sc.Next()
 	}
 	return nil
 }
@@ -146,87 +245,169 @@ func (sc *Scanner) scanIdent(ch int, buf *bytes.Buffer) error {
 	return nil
 }
 
+// scanDigits reads the digits accepted by valid, skipping the '_'
+// separating them in dialects that allow it.
+func (sc *Scanner) scanDigits(buf *bytes.Buffer, valid func(int) bool) error {
+	for ch := sc.Peek(); valid(ch) || ch == '_'; ch = sc.Peek() {
+		sc.Next()
+		if ch != '_' {
+			writeChar(buf, ch)
+		} else if !sc.dialect.has(featDigitSep) {
+			writeChar(buf, ch)
+			return sc.unsupported(buf.String(), "digit separators are not supported in "+sc.dialect.String())
+		}
+	}
+	return nil
+}
+
 func (sc *Scanner) scanDecimal(ch int, buf *bytes.Buffer) error {
 	writeChar(buf, ch)
-	for isDecimal(sc.Peek()) || sc.Peek() == '_' {
-		if sc.Peek() == '_' { 
-			sc.Next()
-			continue 
+	return sc.scanDigits(buf, isDecimal)
+}
+
+// scanPrefixed reads an integer written in base with the prefix 0 followed
+// by the character n. Integers wrap around on overflow like hexadecimal
+// ones.
+func (sc *Scanner) scanPrefixed(n int, buf *bytes.Buffer, base int, valid func(int) bool, name string, tok *ast.Token) error {
+	if !valid(sc.Peek()) {
+		writeChar(buf, '0')
+		writeChar(buf, n)
+		return sc.Error(buf.String(), name+" number expected")
+	}
+	if err := sc.scanDigits(buf, valid); err != nil {
+		return err
+	}
+	var val uint64
+	for _, c := range buf.Bytes() {
+		val = val*uint64(base) + uint64(c-'0')
+	}
+	tok.IsInt, tok.Int, tok.Num = true, int64(val), float64(int64(val))
+	return nil
+}
+
+// scanHex reads a hexadecimal number whose 0x prefix has been consumed.
+// Integers wrap around on overflow like in Lua 5.3.
+func (sc *Scanner) scanHex(buf *bytes.Buffer, tok *ast.Token) error {
+	buf.WriteString("0x")
+	if ch := sc.Peek(); !isDigit(ch) && ch != '.' {
+		return sc.Error(buf.String(), "hex number expected")
+	}
+	if err := sc.scanDigits(buf, isDigit); err != nil {
+		return err
+	}
+	float := false
+	if sc.Peek() == '.' {
+		if !sc.dialect.has(featHexFloat) {
+			return sc.unsupported(buf.String()+".", "hexadecimal floats are not supported in "+sc.dialect.String())
 		}
+		float = true
 		writeChar(buf, sc.Next())
+		if err := sc.scanDigits(buf, isDigit); err != nil {
+			return err
+		}
+	}
+	if ch := sc.Peek(); ch == 'p' || ch == 'P' {
+		if !sc.dialect.has(featHexFloat) {
+			return sc.unsupported(buf.String()+string(rune(ch)), "hexadecimal floats are not supported in "+sc.dialect.String())
+		}
+		float = true
+		if err := sc.scanExponent(buf); err != nil {
+			return err
+		}
+	} else if float {
+		buf.WriteString("p0")
+	}
+	if float {
+		val, err := strconv.ParseFloat(buf.String(), 64)
+		tok.Num = val
+		if err != nil && !errors.Is(err, strconv.ErrRange) {
+			return sc.Error(buf.String(), "malformed number")
+		}
+		return nil
+	}
+
+	var val uint64
+	for _, c := range buf.Bytes()[2:] {
+		digit, _ := strconv.ParseUint(string(c), 16, 8)
+		val = val<<4 | digit
 	}
+	tok.IsInt, tok.Int, tok.Num = true, int64(val), float64(int64(val))
 	return nil
 }
 
-func (sc *Scanner) scanNumber(ch int, buf *bytes.Buffer) (float64, error) {
+// scanExponent reads the exponent of a number, starting with its e or p.
+func (sc *Scanner) scanExponent(buf *bytes.Buffer) error {
+	writeChar(buf, sc.Next())
+	if ch := sc.Peek(); ch == '-' || ch == '+' {
+		writeChar(buf, sc.Next())
+	}
+	if !isDecimal(sc.Peek()) {
+		return sc.Error(buf.String(), "malformed number")
+	}
+	return sc.scanDecimal(sc.Next(), buf)
+}
+
+// scanNumber reads a number starting with ch into tok. Its text without
+// digit separators is written to buf, tok.Str is set to its source text.
+// Numbers without a fraction or an exponent are integers unless they do
+// not fit in an int64, like in Lua 5.3.
+func (sc *Scanner) scanNumber(ch int, buf *bytes.Buffer, tok *ast.Token) error {
+	raw := &sc.raw
+	raw.Reset()
+	writeChar(raw, ch)
+	sc.rec = raw
+	defer func() {
+		sc.rec = nil
+		tok.Str = raw.String()
+	}()
+
 	if ch == '0' {
 		switch sc.Peek() {
 		case 'x', 'X':
-			n := sc.Next()
-			if !isDigit(sc.Peek()) {
-				writeChar(buf, ch)
-				writeChar(buf, n)
-				return 0, sc.Error(buf.String(), "hex number expected")
-			}
-			for isDigit(sc.Peek())  || sc.Peek() == '_' {
-				if sc.Peek() == '_' { 
-					sc.Next()
-					continue 
-				}
-				writeChar(buf, sc.Next())
-			}
-			val, err := strconv.ParseInt(buf.String(), 16, 64)
-			return float64(val), err
+			sc.Next()
+			return sc.scanHex(buf, tok)
 		case 'b', 'B':
 			n := sc.Next()
-			if !isBinary(sc.Peek()) {
-				writeChar(buf, ch)
-				writeChar(buf, n)
-				return 0, sc.Error(buf.String(), "binary number expected")
+			if !sc.dialect.has(featBinary) {
+				return sc.unsupported(string([]byte{'0', byte(n)}), "binary numbers are not supported in "+sc.dialect.String())
 			}
-			for isBinary(sc.Peek()) || sc.Peek() == '_' {
-				if sc.Peek() == '_' { 
-					sc.Next()
-					continue 
-				}
-				writeChar(buf, sc.Next())
-			}
-			val, err := strconv.ParseInt(buf.String(), 2, 64)
-			return float64(val), err
+			return sc.scanPrefixed(n, buf, 2, isBinary, "binary", tok)
 		case 'o', 'O':
 			n := sc.Next()
-			if !isOctal(sc.Peek()) {
-				writeChar(buf, ch)
-				writeChar(buf, n)
-				return 0, sc.Error(buf.String(), "octal number expected")
-			}
-			for isOctal(sc.Peek())  || sc.Peek() == '_' {
-				if sc.Peek() == '_' { 
-					sc.Next()
-					continue 
-				}
-				writeChar(buf, sc.Next())
-			}
-			val, err := strconv.ParseInt(buf.String(), 8, 64)
-			return float64(val), err
-		default:
-			if sc.Peek() != '.' && isDecimal(sc.Peek()) {
-				ch = sc.Next()
+			if !sc.dialect.has(featOctal) {
+				return sc.unsupported(string([]byte{'0', byte(n)}), "octal numbers are not supported in "+sc.dialect.String())
 			}
+			return sc.scanPrefixed(n, buf, 8, isOctal, "octal", tok)
 		}
 	}
-	sc.scanDecimal(ch, buf)
+	float := ch == '.'
+	if err := sc.scanDecimal(ch, buf); err != nil {
+		return err
+	}
 	if sc.Peek() == '.' {
-		sc.scanDecimal(sc.Next(), buf)
+		float = true
+		if err := sc.scanDecimal(sc.Next(), buf); err != nil {
+			return err
+		}
 	}
 	if ch = sc.Peek(); ch == 'e' || ch == 'E' {
-		writeChar(buf, sc.Next())
-		if ch = sc.Peek(); ch == '-' || ch == '+' {
-			writeChar(buf, sc.Next())
+		float = true
+		if err := sc.scanExponent(buf); err != nil {
+			return err
+		}
+	}
+	if !float {
+		if val, err := strconv.ParseInt(buf.String(), 10, 64); err == nil {
+			tok.IsInt, tok.Int, tok.Num = true, val, float64(val)
+			return nil
 		}
-		sc.scanDecimal(sc.Next(), buf)
 	}
-	return strconv.ParseFloat(buf.String(), 64)
+	val, err := strconv.ParseFloat(buf.String(), 64)
+	tok.Num = val
+	if err != nil && !errors.Is(err, strconv.ErrRange) {
+		return sc.Error(buf.String(), "malformed number")
+	}
+	return nil
 }
 
 func (sc *Scanner) scanString(quote int, buf *bytes.Buffer) error {
@@ -238,6 +419,9 @@ func (sc *Scanner) scanString(quote int, buf *bytes.Buffer) error {
 		if ch == '\\' {
 			ch = sc.Next()
 			if ch == 'z' {
+				if !sc.dialect.has(featEscapeHex) {
+					return sc.unsupported(buf.String(), "escape sequence '\\z' is not supported in "+sc.dialect.String())
+				}
 				ch = sc.skipWhiteSpace(whitespace2)
 				continue
 			}
@@ -252,6 +436,59 @@ func (sc *Scanner) scanString(quote int, buf *bytes.Buffer) error {
 	return nil
 }
 
+// scanInterpolated scans a segment of an interpolated string up to the
+// backtick ending the string or the brace starting an expression, and
+// returns the character it stopped at.
+func (sc *Scanner) scanInterpolated(buf *bytes.Buffer) (int, error) {
+	ch := sc.Next()
+	for ch != '`' && ch != '{' {
+		if ch == '\n' || ch == '\r' || ch < 0 {
+			return ch, sc.Error(buf.String(), "unterminated interpolated string")
+		}
+		if ch == '\\' {
+			ch = sc.Next()
+			switch ch {
+			case '`', '{':
+				writeChar(buf, ch)
+			case 'z':
+				ch = sc.skipWhiteSpace(whitespace2)
+				continue
+			default:
+				if err := sc.scanEscape(ch, buf); err != nil {
+					return ch, err
+				}
+			}
+		} else {
+			writeChar(buf, ch)
+		}
+		ch = sc.Next()
+	}
+	if ch == '{' && sc.Peek() == '{' {
+		return ch, sc.Error(buf.String(), "double braces are not permitted within interpolated strings, did you mean '\\{'?")
+	}
+	return ch, nil
+}
+
+// writeUTF8 writes the UTF-8 encoding of val, extended to 6 bytes for
+// values up to 2^31-1 like Lua does. Surrogates are encoded as well.
+func writeUTF8(buf *bytes.Buffer, val int64) {
+	if val < 0x80 {
+		buf.WriteByte(byte(val))
+		return
+	}
+	var tail []byte
+	first := int64(0x3f) // largest value fitting in the first byte
+	for val > first {
+		tail = append(tail, byte(0x80|val&0x3f))
+		val >>= 6
+		first >>= 1
+	}
+	buf.WriteByte(byte(^first<<1 | val))
+	for i := len(tail) - 1; i >= 0; i-- {
+		buf.WriteByte(tail[i])
+	}
+}
+
 func (sc *Scanner) scanEscape(ch int, buf *bytes.Buffer) error {
 	switch ch {
 	case 'a':
@@ -269,6 +506,9 @@ func (sc *Scanner) scanEscape(ch int, buf *bytes.Buffer) error {
 	case 'v':
 		buf.WriteByte('\v')
 	case 'x':
+		if !sc.dialect.has(featEscapeHex) {
+			return sc.unsupported(buf.String(), "escape sequence '\\x' is not supported in "+sc.dialect.String())
+		}
 		var bytes []byte
 		for i := 0; i < 2; i++ {
 			ch = sc.Next()
@@ -280,29 +520,33 @@ func (sc *Scanner) scanEscape(ch int, buf *bytes.Buffer) error {
 		val, _ := strconv.ParseInt(string(bytes), 16, 32)
 		buf.WriteRune(rune(val))
 	case 'u':
-		var index int
-		var bytes []byte
-
+		if !sc.dialect.has(featEscapeUTF8) {
+			return sc.unsupported(buf.String(), "escape sequence '\\u' is not supported in "+sc.dialect.String())
+		}
 		if sc.Next() != '{' {
 			return sc.Error(buf.String(), "{ expected")
 		}
 
-		for {
+		// Lua 5.4 encodes values past Unicode like UTF-8 did originally.
+		max := int64(unicode.MaxRune)
+		if sc.dialect.has(featEscapeUTF8Long) {
+			max = 1<<31 - 1
+		}
+		var val int64
+		for digits := 0; ; digits++ {
 			ch = sc.Next()
-			if ch == '}' && index > 0 {
+			if ch == '}' && digits > 0 {
 				break
 			}
 			if !isDigit(ch) {
 				return sc.Error(buf.String(), "hex digit expected")
 			}
-			if index > 4 {
+			digit, _ := strconv.ParseInt(string(rune(ch)), 16, 64)
+			if val = val<<4 | digit; val > max {
 				return sc.Error(buf.String(), "UTF-8 value too large")
 			}
-			bytes = append(bytes, byte(ch))
-			index++
 		}
-		val, _ := strconv.ParseInt(string(bytes), 16, 32)
-		buf.WriteRune(rune(val))
+		writeUTF8(buf, val)
 	case '\\':
 		buf.WriteByte('\\')
 	case '"':
@@ -339,11 +583,13 @@ func (sc *Scanner) countSep(ch int) (int, int) {
 	return count, ch
 }
 
-func (sc *Scanner) scanMultilineString(ch int, buf *bytes.Buffer) error {
+// scanMultilineString scans a long bracket string and returns its level,
+// the number of = between its brackets.
+func (sc *Scanner) scanMultilineString(ch int, buf *bytes.Buffer) (int, error) {
 	var count1, count2 int
 	count1, ch = sc.countSep(ch)
 	if ch != '[' {
-		return sc.Error(string(ch), "invalid multiline string")
+		return count1, sc.Error(string(rune(ch)), "invalid multiline string")
 	}
 	ch = sc.Next()
 	if ch == '\n' || ch == '\r' {
@@ -351,7 +597,7 @@ func (sc *Scanner) scanMultilineString(ch int, buf *bytes.Buffer) error {
 	}
 	for {
 		if ch < 0 {
-			return sc.Error(buf.String(), "unterminated multiline string")
+			return count1, sc.Error(buf.String(), "unterminated multiline string")
 		} else if ch == ']' {
 			count2, ch = sc.countSep(sc.Next())
 			if count1 == count2 && ch == ']' {
@@ -366,37 +612,37 @@ func (sc *Scanner) scanMultilineString(ch int, buf *bytes.Buffer) error {
 	}
 
 finally:
-	return nil
+	return count1, nil
 }
 
 var reservedWords = map[string]int{
-	"and": TAnd, "break": TBreak, "continue": TContinue, "do": TDo, "else": TElse, "elseif": TElseIf,
+	"and": TAnd, "break": TBreak, "do": TDo, "else": TElse, "elseif": TElseIf,
 	"end": TEnd, "false": TFalse, "for": TFor, "function": TFunction,
 	"if": TIf, "in": TIn, "local": TLocal, "nil": TNil, "not": TNot, "or": TOr,
 	"return": TReturn, "repeat": TRepeat, "then": TThen, "true": TTrue,
 	"until": TUntil, "while": TWhile, "goto": TGoto}
 
-func (sc *Scanner) Scan(lexer *Lexer) (ast.Token, error) {
+// keyword reports whether the reserved word of type typ is a keyword in
+// the dialect of the scanner, otherwise it is a name.
+func (sc *Scanner) keyword(typ int) bool {
+	switch typ {
+	case TGoto:
+		return sc.dialect.has(featGoto)
+	}
+	return true
+}
+
+func (sc *Scanner) Scan() (ast.Token, error) {
 redo:
 	var err error
 	tok := ast.Token{}
-	newline := false
 
-	ch := sc.skipWhiteSpace(whitespace1)
-	if ch == '\n' || ch == '\r' {
-		newline = true
-		ch = sc.skipWhiteSpace(whitespace2)
-	}
+	ch := sc.skipWhiteSpace(whitespace2)
 
-	if ch == '(' && lexer.PrevTokenType == ')' {
-		lexer.PNewLine = newline
-	} else {
-		lexer.PNewLine = false
-	}
-
-	var _buf bytes.Buffer
-	buf := &_buf
+	buf := &sc.buf
+	buf.Reset()
 	tok.Pos = sc.Pos
+	sc.start = sc.Pos
 
 	switch {
 	case isIdent(ch, 0):
@@ -406,12 +652,12 @@ redo:
 		if err != nil {
 			goto finally
 		}
-		if typ, ok := reservedWords[tok.Str]; ok {
+		if typ, ok := reservedWords[tok.Str]; ok && sc.keyword(typ) {
 			tok.Type = typ
 		}
 	case isDecimal(ch):
 		tok.Type = TNumber
-		tok.Num, err = sc.scanNumber(ch, buf)
+		err = sc.scanNumber(ch, buf, &tok)
 	default:
 		switch ch {
 		case EOF:
@@ -420,31 +666,80 @@ redo:
 			ch2 := sc.Peek()
 			switch ch2 {
 			case '-':
-				err = sc.skipComments(sc.Next())
+				sc.Next()
+				err = sc.scanComment(buf)
 				if err != nil {
 					goto finally
 				}
+				c := &ast.Comment{Text: buf.String()}
+				c.SetPos(tok.Pos)
+				c.SetEnd(sc.end())
+				if sc.keepComments {
+					sc.comments = append(sc.comments, c)
+				}
 				goto redo
 			case '=':
 				tok.Type = TCompound
 				tok.Str = "-="
 				sc.Next()
+			case '>':
+				tok.Type = TArrow
+				tok.Str = "->"
+				sc.Next()
 			default:
 				tok.Type = ch
-				tok.Str = string(ch)
+				tok.Str = string(rune(ch))
 			}
 		case '"', '\'':
 			tok.Type = TString
+			tok.Quote = byte(ch)
 			err = sc.scanString(ch, buf)
 			tok.Str = buf.String()
+		case '`':
+			if !sc.dialect.has(featInterp) {
+				writeChar(buf, ch)
+				err = sc.unsupported(buf.String(), "interpolated strings are not supported in "+sc.dialect.String())
+				goto finally
+			}
+			tok.Type = TInterpSimple
+			if ch, err = sc.scanInterpolated(buf); ch == '{' {
+				tok.Type = TInterpBegin
+				sc.interp = append(sc.interp, 0)
+			}
+			tok.Str = buf.String()
+		case '{':
+			if n := len(sc.interp); n > 0 {
+				sc.interp[n-1]++
+			}
+			tok.Type = ch
+			tok.Str = string(rune(ch))
+		case '}':
+			n := len(sc.interp)
+			if n == 0 || sc.interp[n-1] > 0 {
+				if n > 0 {
+					sc.interp[n-1]--
+				}
+				tok.Type = ch
+				tok.Str = string(rune(ch))
+				break
+			}
+			// The brace ends the expression of an interpolated string.
+			sc.interp = sc.interp[:n-1]
+			tok.Type = TInterpEnd
+			if ch, err = sc.scanInterpolated(buf); ch == '{' {
+				tok.Type = TInterpMid
+				sc.interp = append(sc.interp, 0)
+			}
+			tok.Str = buf.String()
 		case '[':
 			if c := sc.Peek(); c == '[' || c == '=' {
 				tok.Type = TString
-				err = sc.scanMultilineString(sc.Next(), buf)
+				tok.Quote = '['
+				tok.Level, err = sc.scanMultilineString(sc.Next(), buf)
 				tok.Str = buf.String()
 			} else {
 				tok.Type = ch
-				tok.Str = string(ch)
+				tok.Str = string(rune(ch))
 			}
 		case '=':
 			if sc.Peek() == '=' {
@@ -453,7 +748,7 @@ redo:
 				sc.Next()
 			} else {
 				tok.Type = ch
-				tok.Str = string(ch)
+				tok.Str = string(rune(ch))
 			}
 		case '~':
 			if sc.Peek() == '=' {
@@ -462,7 +757,7 @@ redo:
 				sc.Next()
 			} else {
 				tok.Type = ch
-				tok.Str = string(ch)
+				tok.Str = string(rune(ch))
 			}
 		case '<':
 			ch2 := sc.Peek()
@@ -475,9 +770,14 @@ redo:
 				tok.Type = TLshift
 				tok.Str = "<<"
 				sc.Next()
+				if sc.Peek() == '=' {
+					tok.Type = TCompound
+					tok.Str = "<<="
+					sc.Next()
+				}
 			default:
 				tok.Type = ch
-				tok.Str = string(ch)
+				tok.Str = string(rune(ch))
 			}
 		case '>':
 			ch2 := sc.Peek()
@@ -490,9 +790,14 @@ redo:
 				tok.Type = TRshift
 				tok.Str = ">>"
 				sc.Next()
+				if sc.Peek() == '=' {
+					tok.Type = TCompound
+					tok.Str = ">>="
+					sc.Next()
+				}
 			default:
 				tok.Type = ch
-				tok.Str = string(ch)
+				tok.Str = string(rune(ch))
 			}
 		case '/':
 			ch2 := sc.Peek()
@@ -501,13 +806,18 @@ redo:
 				tok.Type = TFloorDiv
 				tok.Str = "//"
 				sc.Next()
+				if sc.Peek() == '=' {
+					tok.Type = TCompound
+					tok.Str = "//="
+					sc.Next()
+				}
 			case '=':
 				tok.Type = TCompound
 				tok.Str = "/="
 				sc.Next()
 			default:
 				tok.Type = ch
-				tok.Str = string(ch)
+				tok.Str = string(rune(ch))
 			}
 		case ':':
 			if sc.Peek() == ':' {
@@ -516,14 +826,15 @@ redo:
 				sc.Next()
 			} else {
 				tok.Type = ch
-				tok.Str = string(ch)
+				tok.Str = string(rune(ch))
 			}
 		case '.':
 			ch2 := sc.Peek()
 			switch {
 			case isDecimal(ch2):
 				tok.Type = TNumber
-				tok.Num, err = sc.scanNumber(ch, buf)
+				err = sc.scanNumber(ch, buf, &tok)
+				goto finally
 			case ch2 == '.':
 				writeChar(buf, ch)
 				writeChar(buf, sc.Next())
@@ -548,7 +859,7 @@ redo:
 				sc.Next()
 			} else {
 				tok.Type = ch
-				tok.Str = string(ch)
+				tok.Str = string(rune(ch))
 			}
 		case '*':
 			if sc.Peek() == '=' {
@@ -557,7 +868,7 @@ redo:
 				sc.Next()
 			} else {
 				tok.Type = ch
-				tok.Str = string(ch)
+				tok.Str = string(rune(ch))
 			}
 		case '%':
 			if sc.Peek() == '=' {
@@ -566,7 +877,7 @@ redo:
 				sc.Next()
 			} else {
 				tok.Type = ch
-				tok.Str = string(ch)
+				tok.Str = string(rune(ch))
 			}
 		case '^':
 			if sc.Peek() == '=' {
@@ -575,11 +886,20 @@ redo:
 				sc.Next()
 			} else {
 				tok.Type = ch
-				tok.Str = string(ch)
+				tok.Str = string(rune(ch))
+			}
+		case '&', '|':
+			if sc.Peek() == '=' {
+				tok.Type = TCompound
+				tok.Str = string(rune(ch)) + "="
+				sc.Next()
+			} else {
+				tok.Type = ch
+				tok.Str = string(rune(ch))
 			}
-		case '#', '(', ')', '{', '}', ']', ';', ',', '&', '|':
+		case '#', '(', ')', ']', ';', ',', '?':
 			tok.Type = ch
-			tok.Str = string(ch)
+			tok.Str = string(rune(ch))
 		default:
 			writeChar(buf, ch)
 			err = sc.Error(buf.String(), "Invalid token")
@@ -588,56 +908,11 @@ redo:
 	}
 
 finally:
+	tok.End = sc.end()
 	tok.Name = TokenName(int(tok.Type))
 	return tok, err
 }
 
-// yacc interface {{{
-
-type Lexer struct {
-	scanner       *Scanner
-	Chunk         ast.Chunk
-	PNewLine      bool
-	Token         ast.Token
-	PrevTokenType int
-}
-
-func (lx *Lexer) Lex(lval *yySymType) int {
-	lx.PrevTokenType = lx.Token.Type
-	tok, err := lx.scanner.Scan(lx)
-	if err != nil {
-		panic(err)
-	}
-	if tok.Type < 0 {
-		return 0
-	}
-	lval.token = tok
-	lx.Token = tok
-	return int(tok.Type)
-}
-
-func (lx *Lexer) Error(message string) {
-	panic(lx.scanner.Error(lx.Token.Str, message))
-}
-
-func (lx *Lexer) TokenError(tok ast.Token, message string) {
-	panic(lx.scanner.TokenError(tok, message))
-}
-
-func Parse(reader io.Reader, name string) (chunk ast.Chunk, err error) {
-	lexer := &Lexer{NewScanner(reader, name), nil, false, ast.Token{Str: ""}, TNil}
-	chunk = nil
-	defer func() {
-		if e := recover(); e != nil {
-			err, _ = e.(error)
-		}
-	}()
-	yyParse(lexer)
-	chunk = lexer.Chunk
-	return
-}
-
-// }}}
 
 // Dump {{{
 
@@ -0,0 +1,85 @@
+package parse
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/notnoobmaster/luautil/ast"
+)
+
+// LimitError is the error returned when the input exceeds one of the limits
+// of Options.
+type LimitError struct {
+	Pos   ast.Position // position the limit was exceeded at
+	Limit string       // name of the limit, like "MaxDepth"
+	Max   int          // value of the limit
+}
+
+func (e *LimitError) Error() string {
+	pos := e.Pos
+	return fmt.Sprintf("%v line:%d(column:%d):   %s of %d exceeded\n", pos.Source, pos.Line, pos.Column, e.Limit, e.Max)
+}
+
+// limitedReader reads up to max bytes of r, and reports the end of the
+// input once they are read.
+type limitedReader struct {
+	r        io.Reader
+	left     int
+	exceeded bool // whether r had more than max bytes
+}
+
+func (l *limitedReader) Read(b []byte) (int, error) {
+	if l.left <= 0 {
+		// Read a byte more to know whether the input ends here.
+		var extra [1]byte
+		n, err := io.ReadFull(l.r, extra[:])
+		if n > 0 {
+			l.exceeded = true
+		}
+		if err == io.ErrUnexpectedEOF || err == nil {
+			err = io.EOF
+		}
+		return 0, err
+	}
+	if len(b) > l.left {
+		b = b[:l.left]
+	}
+	n, err := l.r.Read(b)
+	l.left -= n
+	return n, err
+}
+
+// checkLimits returns the error stopping the parser once tok, which was
+// just scanned, goes over MaxBytes or MaxTokens, or once the context is
+// done.
+func (p *parser) checkLimits(tok ast.Token) error {
+	select {
+	case <-p.done:
+		return p.ctx.Err()
+	default:
+	}
+	if p.reader != nil && p.reader.exceeded {
+		return &LimitError{p.scanner.tokenPos(tok), "MaxBytes", p.options.MaxBytes}
+	}
+	p.tokens++
+	if p.options.MaxTokens > 0 && p.tokens > p.options.MaxTokens && tok.Type != EOF {
+		return &LimitError{tok.Pos, "MaxTokens", p.options.MaxTokens}
+	}
+	return nil
+}
+
+// enter increases the nesting depth of the construct being parsed, leave
+// must be deferred after it succeeds. It returns a *LimitError past
+// MaxDepth.
+func (p *parser) enter() error {
+	p.depth++
+	if p.options.MaxDepth > 0 && p.depth > p.options.MaxDepth {
+		p.depth--
+		return &LimitError{p.scanner.tokenPos(p.tok), "MaxDepth", p.options.MaxDepth}
+	}
+	return nil
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
@@ -0,0 +1,137 @@
+package parse
+
+import (
+	"github.com/notnoobmaster/luautil/ast"
+)
+
+// Error recovery
+//
+// Every method of the parser returns the error that made it abandon the
+// construct it parses. A syntax error is an *Error, which stops the parser
+// unless it is tolerant. Other errors, like a *LimitError or the error of
+// the context, always stop it.
+//
+// In tolerant mode a syntax error abandons the innermost statement being
+// parsed, which becomes a BadStmt, or the right hand side of an assignment,
+// which becomes a BadExpr. The parser then skips tokens until the next
+// statement boundary: a keyword that starts or ends a statement, a ';', or
+// a name or '(' that starts a line. The bad nodes span the skipped tokens.
+
+// recovers reports whether the tolerant parser goes on after err.
+func (p *parser) recovers(err error) bool {
+	_, ok := err.(*Error)
+	return ok && p.tolerant
+}
+
+// atBoundary reports whether the current token starts a statement or ends a
+// block.
+func (p *parser) atBoundary() (bool, error) {
+	switch p.tok.Type {
+	case EOF, ';', T2Colon, TBreak, TDo, TElse, TElseIf, TEnd, TFor, TFunction,
+		TGoto, TIf, TLocal, TRepeat, TReturn, TUntil, TWhile:
+		return true, nil
+	case TIdent:
+		if p.lineStart() {
+			return true, nil
+		}
+		return p.isContinue()
+	case '(':
+		return p.lineStart(), nil
+	}
+	return false, nil
+}
+
+// skip skips to the next statement boundary and makes n span the tokens
+// from start to there.
+func (p *parser) skip(n ast.PositionHolder, start ast.Token) error {
+	for {
+		boundary, err := p.atBoundary()
+		if err != nil {
+			return err
+		}
+		if boundary {
+			break
+		}
+		if err := p.next(); err != nil {
+			return err
+		}
+	}
+	pos := start.Pos
+	if start.Type == EOF {
+		pos = p.prevEnd
+	}
+	n.SetPos(pos)
+	if p.prevEnd.Offset > pos.Offset {
+		n.SetEnd(p.prevEnd)
+	} else {
+		n.SetEnd(pos)
+	}
+	return nil
+}
+
+// badStmt ends a statement starting with start that contains a syntax
+// error.
+func (p *parser) badStmt(start ast.Token) (*ast.BadStmt, error) {
+	// Skip at least one token so the parser makes progress.
+	if p.tok.Pos.Offset == start.Pos.Offset && p.tok.Type != EOF {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+	stmt := &ast.BadStmt{}
+	if err := p.skip(stmt, start); err != nil {
+		return nil, err
+	}
+	return stmt, nil
+}
+
+func (p *parser) badExpr(start ast.Token) (*ast.BadExpr, error) {
+	expr := &ast.BadExpr{}
+	if err := p.skip(expr, start); err != nil {
+		return nil, err
+	}
+	return expr, nil
+}
+
+// statementOrBad parses a statement.
+func (p *parser) statementOrBad() (ast.Stmt, error) {
+	start, scope := p.tok, len(p.locals)
+	stmt, err := p.statement()
+	if !p.recovers(err) {
+		return stmt, err
+	}
+	p.closeScope(scope)
+	bad, err := p.badStmt(start)
+	if err != nil {
+		return nil, err
+	}
+	return bad, nil
+}
+
+// exprListOrBad parses the right hand side of an assignment.
+func (p *parser) exprListOrBad() ([]ast.Expr, error) {
+	start := p.tok
+	list, err := p.exprList()
+	if !p.recovers(err) {
+		return list, err
+	}
+	bad, err := p.badExpr(start)
+	if err != nil {
+		return nil, err
+	}
+	return []ast.Expr{bad}, nil
+}
+
+// exprOrBad parses the condition of a repeat statement.
+func (p *parser) exprOrBad() (ast.Expr, error) {
+	start := p.tok
+	expr, err := p.expr()
+	if !p.recovers(err) {
+		return expr, err
+	}
+	bad, err := p.badExpr(start)
+	if err != nil {
+		return nil, err
+	}
+	return bad, nil
+}
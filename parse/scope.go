@@ -0,0 +1,48 @@
+package parse
+
+import (
+	"fmt"
+
+	"github.com/notnoobmaster/luautil/ast"
+)
+
+// local is a local variable in scope. The parser keeps track of them to
+// reject assignments to <const> variables.
+type local struct {
+	name     string
+	constant bool
+}
+
+func (p *parser) declare(name string, constant bool) {
+	p.locals = append(p.locals, local{name, constant})
+}
+
+// closeScope drops the locals declared since the scope opened with n locals
+// in scope.
+func (p *parser) closeScope(n int) {
+	p.locals = p.locals[:n]
+}
+
+// checkAssign reports the names in lhs that refer to a const variable.
+func (p *parser) checkAssign(lhs []ast.Expr) error {
+	for _, expr := range lhs {
+		ident, ok := expr.(*ast.IdentExpr)
+		if !ok {
+			continue
+		}
+		for i := len(p.locals) - 1; i >= 0; i-- {
+			if p.locals[i].name != ident.Value {
+				continue
+			}
+			if p.locals[i].constant {
+				msg := fmt.Sprintf("attempt to assign to const variable '%s'", ident.Value)
+				err := p.fail(&Error{Diagnostic: Diagnostic{Code: CodeConst, Pos: ident.Pos(), End: ident.End(), Token: ident.Value, Message: msg}})
+				if err != nil {
+					return err
+				}
+			}
+			break
+		}
+	}
+	return nil
+}
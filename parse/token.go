@@ -0,0 +1,89 @@
+package parse
+
+// Token types. Tokens made of a single character, like '(' or '+', use the
+// character as their type, the others are numbered after the byte values.
+const (
+	TAnd = iota + 256
+	TBreak
+	TContinue // not scanned, continue is a contextual keyword read as a TIdent
+	TDo
+	TElse
+	TElseIf
+	TEnd
+	TFalse
+	TFor
+	TFunction
+	TIf
+	TIn
+	TLocal
+	TNil
+	TNot
+	TOr
+	TReturn
+	TRepeat
+	TThen
+	TTrue
+	TUntil
+	TWhile
+	TGoto
+	TEqeq
+	TNeq
+	TLte
+	TGte
+	TFloorDiv
+	TRshift
+	TLshift
+	T2Comma
+	T3Comma
+	T2Colon
+	TIdent
+	TNumber
+	TString
+	TCompound
+	TArrow
+	TInterpBegin  // `text{ starting an interpolated string with expressions
+	TInterpMid    // }text{ between two expressions
+	TInterpEnd    // }text` ending an interpolated string
+	TInterpSimple // `text` without expressions
+	TComment      // comment, only returned by a Tokenizer
+	TWhitespace   // whitespace, only returned by a Tokenizer
+)
+
+var tokenNames = [...]string{
+	"TAnd", "TBreak", "TContinue", "TDo", "TElse", "TElseIf", "TEnd", "TFalse",
+	"TFor", "TFunction", "TIf", "TIn", "TLocal", "TNil", "TNot", "TOr",
+	"TReturn", "TRepeat", "TThen", "TTrue", "TUntil", "TWhile", "TGoto",
+	"TEqeq", "TNeq", "TLte", "TGte", "TFloorDiv", "TRshift", "TLshift",
+	"T2Comma", "T3Comma", "T2Colon", "TIdent", "TNumber", "TString", "TCompound", "TArrow",
+	"TInterpBegin", "TInterpMid", "TInterpEnd", "TInterpSimple", "TComment", "TWhitespace",
+}
+
+// tokenTexts holds how tokens are shown in error messages.
+var tokenTexts = [...]string{
+	"'and'", "'break'", "'continue'", "'do'", "'else'", "'elseif'", "'end'", "'false'",
+	"'for'", "'function'", "'if'", "'in'", "'local'", "'nil'", "'not'", "'or'",
+	"'return'", "'repeat'", "'then'", "'true'", "'until'", "'while'", "'goto'",
+	"'=='", "'~='", "'<='", "'>='", "'//'", "'>>'", "'<<'",
+	"'..'", "'...'", "'::'", "<name>", "<number>", "<string>", "<compound assignment>", "'->'",
+	"<interpolated string>", "'}'", "'}'", "<interpolated string>", "<comment>", "<whitespace>",
+}
+
+// TokenName returns the name of the constant for a token type, or the
+// character itself for single character tokens.
+func TokenName(c int) string {
+	if c >= TAnd && c-TAnd < len(tokenNames) {
+		return tokenNames[c-TAnd]
+	}
+	return string([]byte{byte(c)})
+}
+
+// tokenText returns how a token type is shown in error messages.
+func tokenText(c int) string {
+	switch {
+	case c == EOF:
+		return "<eof>"
+	case c >= TAnd && c-TAnd < len(tokenTexts):
+		return tokenTexts[c-TAnd]
+	}
+	return "'" + string(rune(c)) + "'"
+}
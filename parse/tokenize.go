@@ -0,0 +1,279 @@
+package parse
+
+import (
+	"io"
+
+	"github.com/notnoobmaster/luautil/ast"
+)
+
+// TokenKind is the category of a token, for tools like syntax highlighters
+// which do not need the token types of the parser.
+type TokenKind int
+
+const (
+	// KindEOF is the end of the input.
+	KindEOF TokenKind = iota
+	// KindIdent is a name.
+	KindIdent
+	// KindKeyword is a reserved word, like local or end. Words that are only
+	// reserved in some dialects, like goto, are names in the others, and
+	// contextual keywords like continue or type are always names.
+	KindKeyword
+	// KindNumber is a numeric literal.
+	KindNumber
+	// KindString is a quoted or long bracket string.
+	KindString
+	// KindInterpolated is a part of a Luau interpolated string, the text
+	// between its expressions.
+	KindInterpolated
+	// KindOperator is an operator, like + or ==, including the assignment
+	// and compound assignment operators.
+	KindOperator
+	// KindPunctuation is a bracket, a separator or another symbol that is
+	// not an operator, like ( or ,.
+	KindPunctuation
+	// KindComment is a comment.
+	KindComment
+	// KindWhitespace is a run of whitespace.
+	KindWhitespace
+)
+
+func (k TokenKind) String() string {
+	switch k {
+	case KindEOF:
+		return "EOF"
+	case KindIdent:
+		return "identifier"
+	case KindKeyword:
+		return "keyword"
+	case KindNumber:
+		return "number"
+	case KindString:
+		return "string"
+	case KindInterpolated:
+		return "interpolated string"
+	case KindOperator:
+		return "operator"
+	case KindPunctuation:
+		return "punctuation"
+	case KindComment:
+		return "comment"
+	case KindWhitespace:
+		return "whitespace"
+	}
+	return "unknown"
+}
+
+// KindOf returns the kind of a token type.
+func KindOf(typ int) TokenKind {
+	switch typ {
+	case EOF:
+		return KindEOF
+	case TIdent:
+		return KindIdent
+	case TNumber:
+		return KindNumber
+	case TString:
+		return KindString
+	case TInterpBegin, TInterpMid, TInterpEnd, TInterpSimple:
+		return KindInterpolated
+	case TComment:
+		return KindComment
+	case TWhitespace:
+		return KindWhitespace
+	case TEqeq, TNeq, TLte, TGte, TFloorDiv, TRshift, TLshift, T2Comma, TCompound,
+		'+', '-', '*', '/', '%', '^', '#', '&', '~', '|', '<', '>', '=':
+		return KindOperator
+	}
+	if typ >= TAnd && typ <= TGoto {
+		return KindKeyword
+	}
+	return KindPunctuation
+}
+
+// TokenizerOptions configure a Tokenizer.
+type TokenizerOptions struct {
+	// Dialect decides which words are keywords and which tokens are
+	// accepted.
+	Dialect Dialect
+
+	// Comments makes the Tokenizer return comments as TComment tokens
+	// instead of skipping them.
+	Comments bool
+
+	// Whitespace makes the Tokenizer return whitespace as TWhitespace
+	// tokens instead of skipping it. The text of the tokens then covers the
+	// whole input.
+	Whitespace bool
+}
+
+// Tokenizer splits Lua source into tokens without parsing it. It reads the
+// input as it goes, only keeping the text of the token being scanned.
+type Tokenizer struct {
+	src     *tokenSource
+	scanner *Scanner
+	options TokenizerOptions
+
+	pending []ast.Token  // tokens scanned but not returned yet
+	prev    ast.Position // end of the last token or comment scanned
+	err     error
+}
+
+// NewTokenizer returns a Tokenizer reading the source from reader, name is
+// the Source of the positions of the tokens.
+func NewTokenizer(reader io.Reader, name string, opts TokenizerOptions) *Tokenizer {
+	src := &tokenSource{reader: reader}
+	t := &Tokenizer{
+		src:     src,
+		scanner: NewScanner(src, name),
+		options: opts,
+		prev:    ast.Position{Source: name, Line: 1, Column: 1},
+	}
+	t.scanner.dialect = opts.Dialect
+	t.scanner.keepComments = true
+	return t
+}
+
+// tokenSource keeps the input read by the scanner from offset base on, for
+// the text of comments and whitespace. A read error ends the input for the
+// scanner and is kept for the Tokenizer to return.
+type tokenSource struct {
+	reader io.Reader
+	buf    []byte
+	base   int
+	err    error
+}
+
+func (s *tokenSource) Read(b []byte) (int, error) {
+	n, err := s.reader.Read(b)
+	s.buf = append(s.buf, b[:n]...)
+	if err != nil && err != io.EOF {
+		s.err, err = err, io.EOF
+	}
+	return n, err
+}
+
+// text returns the input between the offsets from and to.
+func (s *tokenSource) text(from, to int) []byte {
+	return s.buf[from-s.base : to-s.base]
+}
+
+// end returns the offset of the end of the input read so far.
+func (s *tokenSource) end() int {
+	return s.base + len(s.buf)
+}
+
+// discard drops the input before offset.
+func (s *tokenSource) discard(offset int) {
+	s.buf = s.buf[offset-s.base:]
+	s.base = offset
+}
+
+// Next returns the next token. Its Type is the type used by the parser, see
+// KindOf for its kind. At the end of the input Next returns a token of type
+// EOF, and keeps doing so. After an error Next keeps returning the error.
+func (t *Tokenizer) Next() (ast.Token, error) {
+	if len(t.pending) == 0 {
+		if t.err != nil {
+			return ast.Token{}, t.err
+		}
+		t.scan()
+	}
+	if len(t.pending) == 0 {
+		return ast.Token{}, t.err
+	}
+	tok := t.pending[0]
+	t.pending = t.pending[1:]
+	return tok, nil
+}
+
+// scan scans the next token and queues it after the comments and whitespace
+// before it.
+func (t *Tokenizer) scan() {
+	tok, err := t.scanner.Scan()
+	if t.src.err != nil {
+		err = t.src.err
+	}
+	if err != nil {
+		t.err = err
+		return
+	}
+	for _, c := range t.scanner.comments {
+		t.gap(c.Pos().Offset)
+		text := t.src.text(c.Pos().Offset, c.End().Offset)
+		if t.options.Comments {
+			t.pending = append(t.pending, t.token(TComment, text))
+		} else {
+			t.prev = advance(t.prev, text)
+		}
+	}
+	t.scanner.comments = t.scanner.comments[:0]
+
+	if tok.Type == EOF {
+		t.gap(t.src.end())
+		tok.Pos = t.prev
+		tok.End = t.prev
+	} else {
+		t.gap(tok.Pos.Offset)
+		t.prev = tok.End
+	}
+	t.pending = append(t.pending, tok)
+	t.src.discard(t.prev.Offset)
+}
+
+// gap queues the whitespace from the end of the last token to offset.
+func (t *Tokenizer) gap(offset int) {
+	if offset <= t.prev.Offset {
+		return
+	}
+	text := t.src.text(t.prev.Offset, offset)
+	if t.options.Whitespace {
+		t.pending = append(t.pending, t.token(TWhitespace, text))
+		return
+	}
+	t.prev = advance(t.prev, text)
+}
+
+// token returns a token of text starting at the end of the last one.
+func (t *Tokenizer) token(typ int, text []byte) ast.Token {
+	tok := ast.Token{Type: typ, Name: TokenName(typ), Str: string(text), Pos: t.prev}
+	tok.End = advance(t.prev, text)
+	t.prev = tok.End
+	return tok
+}
+
+// advance returns the position after text, which starts at pos.
+func advance(pos ast.Position, text []byte) ast.Position {
+	for i := 0; i < len(text); i++ {
+		switch ch := text[i]; ch {
+		case '\n', '\r':
+			if i+1 < len(text) && text[i+1] != ch && (text[i+1] == '\n' || text[i+1] == '\r') {
+				i++
+				pos.Offset++
+			}
+			pos.Line++
+			pos.Column = 1
+		default:
+			pos.Column++
+		}
+		pos.Offset++
+	}
+	return pos
+}
+
+// Tokenize returns the tokens of the source read from reader, comments
+// included, up to the end of the input which is not returned.
+func Tokenize(reader io.Reader, name string) ([]ast.Token, error) {
+	t := NewTokenizer(reader, name, TokenizerOptions{Comments: true})
+	var tokens []ast.Token
+	for {
+		tok, err := t.Next()
+		if err != nil {
+			return tokens, err
+		}
+		if tok.Type == EOF {
+			return tokens, nil
+		}
+		tokens = append(tokens, tok)
+	}
+}
@@ -0,0 +1,613 @@
+package parse
+
+import (
+	"github.com/notnoobmaster/luautil/ast"
+)
+
+// Luau types {{{
+
+// isTypeAlias reports whether the current token starts a type alias, type
+// and export are not keywords so they are recognized by the tokens after.
+func (p *parser) isTypeAlias() (bool, error) {
+	if p.tok.Type != TIdent || !p.dialect.has(featTypes) {
+		return false, nil
+	}
+	next, err := p.peek()
+	if err != nil {
+		return false, err
+	}
+	switch p.tok.Str {
+	case "type":
+		return next.Type == TIdent, nil
+	case "export":
+		return next.Type == TIdent && next.Str == "type", nil
+	}
+	return false, nil
+}
+
+func (p *parser) typeAlias() (ast.Stmt, error) {
+	stmt := &ast.TypeAliasStmt{}
+	stmt.SetPos(p.tok.Pos)
+	if p.tok.Str == "export" {
+		stmt.Export = true
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	name, err := p.expect(TIdent)
+	if err != nil {
+		return nil, err
+	}
+	stmt.Name = name.Str
+	if p.tok.Type == '<' {
+		if stmt.Generics, stmt.Defaults, err = p.generics(true); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := p.expect('='); err != nil {
+		return nil, err
+	}
+	if stmt.Type, err = p.typ(); err != nil {
+		return nil, err
+	}
+	stmt.SetEnd(stmt.Type.End())
+	return stmt, nil
+}
+
+// annotation parses the type following a ':' after a name.
+func (p *parser) annotation() (ast.Type, error) {
+	if err := p.require(featTypes, "type annotations are"); err != nil {
+		return nil, err
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	return p.typ()
+}
+
+// cast parses the type assertions following expr, if any.
+func (p *parser) cast(expr ast.Expr) (ast.Expr, error) {
+	// In dialects with labels a '::' that starts a line is taken as the
+	// start of a label.
+	for p.tok.Type == T2Colon && p.dialect.has(featTypes) && !(p.dialect.has(featGoto) && p.lineStart()) {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		typ, err := p.typ()
+		if err != nil {
+			return nil, err
+		}
+		cast := &ast.TypeCastExpr{Expr: expr, Type: typ}
+		cast.SetPos(expr.Pos())
+		cast.SetEnd(typ.End())
+		expr = cast
+	}
+	return expr, nil
+}
+
+// genericNames parses the generic names of a function or a type alias.
+func (p *parser) genericNames() ([]string, error) {
+	names, _, err := p.generics(false)
+	return names, err
+}
+
+// generics parses the generic names of a type alias with their default
+// types, which are nil for the names without one. defaults is nil if no
+// name has one or if they are not allowed.
+func (p *parser) generics(allowDefaults bool) (names []string, defaults []ast.Type, err error) {
+	if err := p.require(featTypes, "generics are"); err != nil {
+		return nil, nil, err
+	}
+	if err := p.next(); err != nil {
+		return nil, nil, err
+	}
+	names = []string{}
+	for {
+		tok, err := p.expect(TIdent)
+		if err != nil {
+			return nil, nil, err
+		}
+		name := tok.Str
+		if p.tok.Type == T3Comma {
+			name += "..."
+			if err := p.next(); err != nil {
+				return nil, nil, err
+			}
+		}
+		names = append(names, name)
+		if p.tok.Type == '=' {
+			if !allowDefaults {
+				return nil, nil, p.error("generic defaults are only allowed in type aliases")
+			}
+			if err := p.next(); err != nil {
+				return nil, nil, err
+			}
+			for len(defaults) < len(names)-1 {
+				defaults = append(defaults, nil)
+			}
+			def, err := p.genericDefault()
+			if err != nil {
+				return nil, nil, err
+			}
+			defaults = append(defaults, def)
+		} else if defaults != nil {
+			return nil, nil, p.error("default type expected after " + name)
+		}
+		if p.tok.Type != ',' {
+			break
+		}
+		if err := p.next(); err != nil {
+			return nil, nil, err
+		}
+	}
+	if _, err := p.closeAngle(); err != nil {
+		return nil, nil, err
+	}
+	return names, defaults, nil
+}
+
+// genericDefault parses the default type of a generic name.
+func (p *parser) genericDefault() (ast.Type, error) {
+	switch p.tok.Type {
+	case T3Comma:
+		types, _, err := p.typeList()
+		if err != nil {
+			return nil, err
+		}
+		return types[0], nil
+	case '(':
+	default:
+		return p.typ()
+	}
+	open := p.tok
+	t, _, err := p.parenType(true)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, p.errorAt(p.scanner.TokenError(open, "type pack lists are not supported as generic defaults"))
+	}
+	return p.typeRest(t)
+}
+
+// closeAngle consumes the '>' closing a list of generics. Tokens starting
+// with '>', like the '>>' ending nested lists, are split.
+func (p *parser) closeAngle() (ast.Token, error) {
+	if p.tok.Type != TRshift && p.tok.Type != TGte && p.tok.Str != ">>=" {
+		return p.expect('>')
+	}
+	closer, rest := p.tok, p.tok
+	rest.Pos.Column++
+	rest.Pos.Offset++
+	rest.Str = rest.Str[1:]
+	switch rest.Str {
+	case ">":
+		rest.Type = '>'
+	case "=":
+		rest.Type = '='
+	case ">=":
+		rest.Type = TGte
+	}
+	rest.Name = TokenName(rest.Type)
+	closer.Type, closer.Str, closer.Name, closer.End = '>', ">", TokenName('>'), rest.Pos
+
+	p.tok = rest
+	p.prevType, p.prevEnd = closer.Type, closer.End
+	return closer, nil
+}
+
+// typ parses a type.
+func (p *parser) typ() (ast.Type, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+	// A union or an intersection may start with its operator.
+	if p.tok.Type == '|' || p.tok.Type == '&' {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+	t, err := p.simpleType()
+	if err != nil {
+		return nil, err
+	}
+	return p.typeRest(t)
+}
+
+// typeRest parses the operators following the type t.
+func (p *parser) typeRest(t ast.Type) (ast.Type, error) {
+	t, err := p.intersectionRest(t)
+	if err != nil || p.tok.Type != '|' {
+		return t, err
+	}
+	union := &ast.UnionType{Types: []ast.Type{t}}
+	for p.tok.Type == '|' {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if t, err = p.simpleType(); err != nil {
+			return nil, err
+		}
+		if t, err = p.intersectionRest(t); err != nil {
+			return nil, err
+		}
+		union.Types = append(union.Types, t)
+	}
+	union.SetPos(union.Types[0].Pos())
+	union.SetEnd(t.End())
+	return union, nil
+}
+
+// intersectionRest parses the intersection and the optional types
+// following the type t.
+func (p *parser) intersectionRest(t ast.Type) (ast.Type, error) {
+	t, err := p.optionalRest(t)
+	if err != nil || p.tok.Type != '&' {
+		return t, err
+	}
+	inter := &ast.IntersectionType{Types: []ast.Type{t}}
+	for p.tok.Type == '&' {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if t, err = p.simpleType(); err != nil {
+			return nil, err
+		}
+		if t, err = p.optionalRest(t); err != nil {
+			return nil, err
+		}
+		inter.Types = append(inter.Types, t)
+	}
+	inter.SetPos(inter.Types[0].Pos())
+	inter.SetEnd(t.End())
+	return inter, nil
+}
+
+func (p *parser) optionalRest(t ast.Type) (ast.Type, error) {
+	for p.tok.Type == '?' {
+		opt := &ast.OptionalType{Type: t}
+		opt.SetPos(t.Pos())
+		opt.SetEnd(p.tok.End)
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		t = opt
+	}
+	return t, nil
+}
+
+func (p *parser) simpleType() (ast.Type, error) {
+	start := p.tok
+	switch p.tok.Type {
+	case TNil:
+		t := &ast.TypeReference{Name: "nil"}
+		t.SetPos(start.Pos)
+		t.SetEnd(start.End)
+		return t, p.next()
+	case TTrue, TFalse, TString:
+		value, err := p.simpleExpr()
+		if err != nil {
+			return nil, err
+		}
+		t := &ast.SingletonType{Value: value.(ast.ConstExpr)}
+		t.SetPos(start.Pos)
+		t.SetEnd(start.End)
+		return t, nil
+	case TIdent:
+		if p.tok.Str == "typeof" {
+			next, err := p.peek()
+			if err != nil {
+				return nil, err
+			}
+			if next.Type == '(' {
+				return p.typeofType()
+			}
+		}
+		return p.typeReference()
+	case '{':
+		return p.tableType()
+	case '(', '<':
+		t, _, err := p.parenType(false)
+		return t, err
+	}
+	return nil, p.error("type expected")
+}
+
+// typeofType parses typeof with the expression in parentheses after it.
+func (p *parser) typeofType() (ast.Type, error) {
+	start := p.tok
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	open, err := p.expect('(')
+	if err != nil {
+		return nil, err
+	}
+	expr, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	close, err := p.expectClose(')', open)
+	if err != nil {
+		return nil, err
+	}
+	t := &ast.TypeofType{Expr: expr}
+	t.SetPos(start.Pos)
+	t.SetEnd(close.End)
+	return t, nil
+}
+
+// typeReference parses the name of a type with its prefix and its
+// parameters.
+func (p *parser) typeReference() (ast.Type, error) {
+	start := p.tok
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	t := &ast.TypeReference{Name: start.Str}
+	if p.tok.Type == '.' {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		name, err := p.expect(TIdent)
+		if err != nil {
+			return nil, err
+		}
+		t.Prefix, t.Name = start.Str, name.Str
+	}
+	if p.tok.Type == '<' {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		t.Params = []ast.Type{}
+		if p.tok.Type != '>' {
+			params, _, err := p.typeList()
+			if err != nil {
+				return nil, err
+			}
+			t.Params = params
+		}
+		if _, err := p.closeAngle(); err != nil {
+			return nil, err
+		}
+	} else if p.tok.Type == T3Comma && t.Prefix == "" {
+		// A generic pack like T..., named like in generic lists.
+		t.Name += "..."
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+	t.SetPos(start.Pos)
+	t.SetEnd(p.prevEnd)
+	return t, nil
+}
+
+// typeList parses a list of types, optionally named and ending with a
+// variadic type, like the parameters of a function type. names is nil if
+// no type has a name.
+func (p *parser) typeList() (types []ast.Type, names []string, err error) {
+	for {
+		if p.tok.Type == T3Comma {
+			start := p.tok
+			if err := p.next(); err != nil {
+				return nil, nil, err
+			}
+			inner, err := p.typ()
+			if err != nil {
+				return nil, nil, err
+			}
+			t := &ast.VariadicType{Type: inner}
+			t.SetPos(start.Pos)
+			t.SetEnd(inner.End())
+			types = append(types, t)
+			break
+		}
+		if p.tok.Type == TIdent {
+			next, err := p.peek()
+			if err != nil {
+				return nil, nil, err
+			}
+			if next.Type == ':' {
+				for len(names) < len(types) {
+					names = append(names, "")
+				}
+				names = append(names, p.tok.Str)
+				if err := p.next(); err != nil {
+					return nil, nil, err
+				}
+				if err := p.next(); err != nil {
+					return nil, nil, err
+				}
+			}
+		}
+		t, err := p.typ()
+		if err != nil {
+			return nil, nil, err
+		}
+		types = append(types, t)
+		if p.tok.Type != ',' {
+			break
+		}
+		if err := p.next(); err != nil {
+			return nil, nil, err
+		}
+	}
+	if names != nil {
+		for len(names) < len(types) {
+			names = append(names, "")
+		}
+	}
+	return types, names, nil
+}
+
+// parenType parses a function type or a type in parentheses. If pack is
+// set it also accepts a list of types in parentheses, which is returned as
+// list instead.
+func (p *parser) parenType(pack bool) (t ast.Type, list []ast.Type, err error) {
+	start := p.tok
+	fn := &ast.FunctionType{Params: []ast.Type{}}
+	if p.tok.Type == '<' {
+		if fn.Generics, err = p.genericNames(); err != nil {
+			return nil, nil, err
+		}
+	}
+	open, err := p.expect('(')
+	if err != nil {
+		return nil, nil, err
+	}
+	if p.tok.Type != ')' {
+		if fn.Params, fn.ParamNames, err = p.typeList(); err != nil {
+			return nil, nil, err
+		}
+	}
+	close, err := p.expectClose(')', open)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if p.tok.Type != TArrow && fn.Generics == nil && fn.ParamNames == nil {
+		if len(fn.Params) == 1 {
+			if _, ok := fn.Params[0].(*ast.VariadicType); !ok {
+				inner := fn.Params[0]
+				inner.SetPos(start.Pos)
+				inner.SetEnd(close.End)
+				return inner, nil, nil
+			}
+		}
+		if pack {
+			return nil, fn.Params, nil
+		}
+	}
+	if _, err := p.expect(TArrow); err != nil {
+		return nil, nil, err
+	}
+	if fn.Returns, err = p.returnTypes(); err != nil {
+		return nil, nil, err
+	}
+	fn.SetPos(start.Pos)
+	fn.SetEnd(p.prevEnd)
+	return fn, nil, nil
+}
+
+// returnTypes parses the return types of a function: a single type, a
+// variadic type, or a list of types in parentheses.
+func (p *parser) returnTypes() ([]ast.Type, error) {
+	if p.tok.Type == T3Comma {
+		types, _, err := p.typeList()
+		return types, err
+	}
+	if p.tok.Type != '(' {
+		t, err := p.typ()
+		if err != nil {
+			return nil, err
+		}
+		return []ast.Type{t}, nil
+	}
+	t, list, err := p.parenType(true)
+	if err != nil || t == nil {
+		return list, err
+	}
+	if t, err = p.typeRest(t); err != nil {
+		return nil, err
+	}
+	return []ast.Type{t}, nil
+}
+
+func (p *parser) tableType() (ast.Type, error) {
+	open, err := p.expect('{')
+	if err != nil {
+		return nil, err
+	}
+	t := &ast.TableType{}
+	array := p.tok.Type != '}' && p.tok.Type != '['
+	if array && p.tok.Type == TIdent {
+		next, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+		array = next.Type != ':'
+	}
+	if array {
+		if t.Array, err = p.typ(); err != nil {
+			return nil, err
+		}
+	} else {
+		t.Fields = []*ast.TableTypeField{}
+	}
+	for t.Array == nil && p.tok.Type != '}' {
+		field, err := p.tableTypeField()
+		if err != nil {
+			return nil, err
+		}
+		t.Fields = append(t.Fields, field)
+		if p.tok.Type != ',' && p.tok.Type != ';' {
+			break
+		}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+	close, err := p.expectClose('}', open)
+	if err != nil {
+		return nil, err
+	}
+	t.SetPos(open.Pos)
+	t.SetEnd(close.End)
+	return t, nil
+}
+
+func (p *parser) tableTypeField() (*ast.TableTypeField, error) {
+	field := &ast.TableTypeField{}
+	field.SetPos(p.tok.Pos)
+	if p.tok.Type == '[' {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		key, err := p.typ()
+		if err != nil {
+			return nil, err
+		}
+		field.Key = key
+		if _, err := p.expect(']'); err != nil {
+			return nil, err
+		}
+	} else {
+		name, err := p.expect(TIdent)
+		if err != nil {
+			return nil, err
+		}
+		field.Name = name.Str
+	}
+	if _, err := p.expect(':'); err != nil {
+		return nil, err
+	}
+	value, err := p.typ()
+	if err != nil {
+		return nil, err
+	}
+	field.Value = value
+	field.SetEnd(value.End())
+	return field, nil
+}
+
+// padTypes makes list as long as names, unless it is nil.
+func padTypes(list []ast.Type, names []string) []ast.Type {
+	for list != nil && len(list) < len(names) {
+		list = append(list, nil)
+	}
+	return list
+}
+
+// setType sets the type of the last of names in list.
+func setType(list []ast.Type, names []string, t ast.Type) []ast.Type {
+	for len(list) < len(names)-1 {
+		list = append(list, nil)
+	}
+	return append(list, t)
+}
+
+// }}}
@@ -17,6 +17,24 @@ func Quote(s string) string {
 	return b.String()
 }
 
+// QuoteWith is like Quote but quotes s with quote, which is '"' or '\''.
+func QuoteWith(s string, quote byte) string {
+	b := &strings.Builder{}
+	b.Grow(3*len(s)/2)
+	quoteWith(b, s, quote)
+	return b.String()
+}
+
+// EscapeInterpolated returns s escaped for use as a literal segment of a
+// Luau interpolated string, without the enclosing backticks. Braces and
+// backticks are backslashed on top of the escapes used by Quote.
+func EscapeInterpolated(s string) string {
+	b := &strings.Builder{}
+	b.Grow(3*len(s)/2)
+	escapeWith(b, s, '`')
+	return b.String()
+}
+
 func convert(char int) string {
 	if char < 10 {
 		return "00" + strconv.Itoa(char)
@@ -28,6 +46,11 @@ func convert(char int) string {
 
 func quoteWith(b *strings.Builder, s string, quote byte) {
 	b.WriteByte(quote)
+	escapeWith(b, s, quote)
+	b.WriteByte(quote)
+}
+
+func escapeWith(b *strings.Builder, s string, quote byte) {
 	for width := 0; len(s) > 0; s = s[width:] {
 		r := rune(s[0])
 		width = 1
@@ -41,12 +64,11 @@ func quoteWith(b *strings.Builder, s string, quote byte) {
 		}
 		appendEscapedRune(b, r, quote)
 	}
-	b.WriteByte(quote)
 }
 
 func appendEscapedRune(b *strings.Builder, r rune, quote byte) {
 	var runeTmp [utf8.UTFMax]byte
-	if r == rune(quote) || r == '\\' { // always backslashed
+	if r == rune(quote) || r == '\\' || quote == '`' && r == '{' { // always backslashed
 		b.WriteRune('\\')
 		b.WriteRune(r)
 		return
@@ -1,22 +1,33 @@
 package luautil 
 
 import (
+	"fmt"
 	"strconv"
 	"testing"
 )
 
 func TestAll(t *testing.T) {
 	var test []byte
-	var expected string
+	expected := `"`
 	for i := 0; i < 256; i++ {
 		test = append(test, byte(i))
-		expected += "\\" + strconv.Itoa(i)
+		switch {
+		case '\a' <= i && i <= '\r':
+			expected += `\` + string("abtnvfr"[i-'\a'])
+		case i == '"' || i == '\\':
+			expected += `\` + string(rune(i))
+		case ' ' <= i && i < 127:
+			expected += string(rune(i))
+		default:
+			expected += fmt.Sprintf("\\%03d", i)
+		}
 	}
+	expected += `"`
 
 	result := Quote(string(test))
 
 	if expected != result {
-		t.Errorf("Expected got %s", result)
+		t.Errorf("Expected %s, got %s", expected, result)
 	}
 }
 
@@ -1,11 +1,14 @@
 package tests
 
 import (
+	"bytes"
 	_ "embed"
+	"math"
 	"os"
 	"strings"
 	"testing"
 
+	"github.com/notnoobmaster/luautil/ast"
 	"github.com/notnoobmaster/luautil/parse"
 )
 
@@ -152,6 +155,49 @@ var gotolabel = []string{
 	"::label::;\n",
 }
 
+var ifexpr = []string{
+	"_ = if _ then _ else _;\n",
+	"_ = if _ then _ elseif _ then _ else _;\n",
+	"_ = if _ then _ elseif _ then _ elseif _ then _ else _ + _;\n",
+	"_ = (if _ then _ else _) + _;\n",
+	"_ = _ + (if _ then _ else _);\n",
+	"_ = -(if _ then _ else _);\n",
+	"_ = (if _ then _ else _)();\n",
+	"_ = (if _ then _ else _).a;\n",
+	"_ = if if _ then _ else _ then _ else _;\n",
+	"_(if _ then _ else _);\n",
+}
+
+var compound = []string{
+	"_ += _;\n",
+	"_ -= _;\n",
+	"_ *= _;\n",
+	"_ /= _;\n",
+	"_ //= _;\n",
+	"_ %= _;\n",
+	"_ ^= _;\n",
+	"_ ..= _;\n",
+	"_ &= _;\n",
+	"_ |= _;\n",
+	"_ <<= _;\n",
+	"_ >>= _;\n",
+	"_._[_] += _ + _;\n",
+}
+
+var interpolated = []string{
+	"_ = `_`;\n",
+	"_ = ``;\n",
+	"_ = `_{_}_`;\n",
+	"_ = `{_}{_}`;\n",
+	"_ = `_ {_ + _} _ {#_} _`;\n",
+	"_ = `\\{_}\\``;\n",
+	"_ = `\\n\\\\`;\n",
+	"_ = `{ {} }`;\n",
+	"_ = `{`{_}`}`;\n",
+	"_ = `{_({}, `{_}`)}`;\n",
+	"_ = (`_`):_();\n",
+}
+
 var tests = map[string][]string{
 	"Assignment": assignment,
 	"Structures":    control,
@@ -168,6 +214,9 @@ var tests = map[string][]string{
 	"Definitions": definitions,
 	"Bitwise": bitwise,
 	"Goto&Labels": gotolabel,
+	"IfExpr": ifexpr,
+	"Interpolated": interpolated,
+	"Compound": compound,
 }
 
 func TestFormat(t *testing.T) {
@@ -187,34 +236,113 @@ func TestFormat(t *testing.T) {
 }
 
 func TestNumbers(t *testing.T) {
-	numbers := []string{
-		"_ = 0;\n",
-		"_ = 0.0;\n",
-
-		"_ = 0.0e0;\n",
-		"_ = 0.0e+0;\n",
-		"_ = 0.0e-0;\n",
-
-		"_ = 0x0;\n",
-		"_ = 0X0;\n",
-		"_ = 0x0_0__0;\n",
-		
-		"_ = 0b0;\n",
-		"_ = 0B0;\n",
-		"_ = 0b0_0__0;\n",
-
-		"_ = 0o0;\n",
-		"_ = 0O0;\n",
-		"_ = 0o0_0__0;\n",
+	tests := []struct {
+		src       string
+		integer   bool
+		value     float64
+		canonical string
+	}{
+		{"0", true, 0, "0"},
+		{"0.0", false, 0, "0.0"},
+		{"3", true, 3, "3"},
+		{"3.0", false, 3, "3.0"},
+		{".5", false, 0.5, "0.5"},
+		{"007", true, 7, "7"},
+
+		{"0.0e0", false, 0, "0.0"},
+		{"0.0e+0", false, 0, "0.0"},
+		{"1e-0", false, 1, "1.0"},
+		{"1E2", false, 100, "100.0"},
+		{"1e309", false, math.Inf(1), "1e999"},
+		{"1e-7", false, 1e-7, "1e-07"},
+
+		{"0x0", true, 0, "0"},
+		{"0X0", true, 0, "0"},
+		{"0xff", true, 255, "255"},
+		{"0x0_0__0", true, 0, "0"},
+		{"0x7FFFFFFFFFFFFFFF", true, math.MaxInt64, "9223372036854775807"},
+		{"0xFFFFFFFFFFFFFFFF", true, -1, "0xffffffffffffffff"},
+		{"0x1p4", false, 16, "16.0"},
+		{"0x.8", false, 0.5, "0.5"},
+		{"9223372036854775807", true, math.MaxInt64, "9223372036854775807"},
+		{"9223372036854775808", false, 9223372036854775808, "9223372036854776000.0"},
+
+		{"0b0", true, 0, "0"},
+		{"0B101", true, 5, "5"},
+		{"0b0_0__0", true, 0, "0"},
+		{"0b" + strings.Repeat("1", 64), true, -1, "0xffffffffffffffff"},
+		{"0b1" + strings.Repeat("0", 64), true, 0, "0"},
+
+		{"0o0", true, 0, "0"},
+		{"0O17", true, 15, "15"},
+		{"0o0_0__0", true, 0, "0"},
+		{"0o1" + strings.Repeat("7", 21), true, -1, "0xffffffffffffffff"},
+		{"0o" + strings.Repeat("7", 30), true, -1, "0xffffffffffffffff"},
 	}
 
-	for _, s := range numbers {
-		chunk, err := parse.Parse(strings.NewReader(s), "")
+	for _, test := range tests {
+		src := "_ = " + test.src + ";\n"
+		chunk, err := parse.Parse(strings.NewReader(src), "")
 		if err != nil {
+			t.Fatalf("%s: %v", test.src, err)
+		}
+		if chunk.String() != src {
+			t.Fatalf("\nGot:\n%sExpected:\n%s", chunk, src)
+		}
+		n := chunk[0].(*ast.AssignStmt).Rhs[0].(*ast.NumberExpr)
+		if n.Integer != test.integer || n.Value != test.value || n.Integer && float64(n.Int) != n.Value {
+			t.Errorf("%s: got integer %v, value %v, int %d", test.src, n.Integer, n.Value, n.Int)
+		}
+		if got := n.Canonical(); got != test.canonical {
+			t.Errorf("%s: expected canonical %s, got %s", test.src, test.canonical, got)
+		}
+		if n.Raw = ""; n.String() != test.canonical {
+			t.Errorf("%s: expected %s without Raw, got %s", test.src, test.canonical, n.String())
+		}
+	}
+
+	stale := []struct {
+		number   ast.NumberExpr
+		expected string
+	}{
+		{ast.NumberExpr{Value: 255, Integer: true, Int: 255, Raw: "0xff"}, "0xff"},
+		{ast.NumberExpr{Value: 256, Integer: true, Int: 256, Raw: "0xff"}, "256"},
+		{ast.NumberExpr{Value: 255, Raw: "0xff"}, "255.0"},
+		{ast.NumberExpr{Value: 1.5, Raw: "1_000"}, "1.5"},
+		{ast.NumberExpr{Value: 2, Integer: true, Int: 2, Raw: "x"}, "2"},
+	}
+	for _, test := range stale {
+		if got := test.number.String(); got != test.expected {
+			t.Errorf("Expected %v spelled %s to print as %s, got %s", test.number.Value, test.number.Raw, test.expected, got)
+		}
+	}
+}
+
+func TestNegativeNumbers(t *testing.T) {
+	num := func(v float64) *ast.NumberExpr { return &ast.NumberExpr{Value: v} }
+	tests := []struct {
+		expr     ast.Expr
+		expected string
+	}{
+		{num(-5), "-5.0"},
+		{&ast.ArithmeticOpExpr{Operator: ast.OpPow, Lhs: num(-2), Rhs: num(2)}, "(-2.0) ^ 2.0"},
+		{&ast.ArithmeticOpExpr{Operator: ast.OpSub, Lhs: num(-2), Rhs: num(-1)}, "-2.0 - (-1.0)"},
+		{&ast.UnaryOpExpr{Operator: ast.OpNeg, Expr: num(-1)}, "-(-1.0)"},
+		{&ast.UnaryOpExpr{Operator: ast.OpNeg, Expr: num(math.Inf(-1))}, "-(-1e999)"},
+	}
+
+	for _, test := range tests {
+		chunk := ast.Chunk{&ast.ReturnStmt{Exprs: []ast.Expr{test.expr}}}
+		expected := "return " + test.expected + ";\n"
+		if got := chunk.String(); got != expected {
+			t.Errorf("Expected %q, got %q", expected, got)
+		}
+		var buf bytes.Buffer
+		if err := ast.Fprint(&buf, chunk, &ast.PrintConfig{MaxWidth: 80}); err != nil {
 			t.Fatal(err)
 		}
-		if chunk.String() != "_ = 0;\n" {
-			t.Fatalf("\nGot:\n%sExpected:\n_ = 0;\n", chunk)
+		if got := buf.String(); got != expected {
+			t.Errorf("Expected %q with MaxWidth, got %q", expected, got)
 		}
 	}
 }
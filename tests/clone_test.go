@@ -0,0 +1,119 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/notnoobmaster/luautil/ast"
+)
+
+const cloneSrc = `local function f(a, ...)
+	local t = {a, b = 2, [3] = {}}
+	for i = 1, #t do
+		t[i] = t[i] .. "!"
+	end
+	return f:g(a or not b)
+end
+`
+
+func TestClone(t *testing.T) {
+	chunk := mustParse(t, cloneSrc)
+	clone := ast.Clone(chunk).(ast.Chunk)
+
+	if !ast.Equal(chunk, clone, nil) {
+		t.Fatalf("Clone differs from original:\n%s", clone)
+	}
+	if clone.String() != chunk.String() {
+		t.Fatalf("\nGot:\n%sExpected:\n%s", clone, chunk)
+	}
+
+	fn := clone[0].(*ast.LocalFunctionStmt)
+	fn.Name = "g"
+	fn.Func.ParList.Names[0] = "b"
+	fn.Func.Chunk = fn.Func.Chunk[:1]
+
+	orig := chunk[0].(*ast.LocalFunctionStmt)
+	if orig.Name != "f" || orig.Func.ParList.Names[0] != "a" || len(orig.Func.Chunk) != 3 {
+		t.Fatalf("Modifying the clone changed the original:\n%s", chunk)
+	}
+	if ast.Equal(chunk, clone, nil) {
+		t.Fatal("Expected modified clone to differ from original")
+	}
+}
+
+func TestClonePositions(t *testing.T) {
+	chunk := mustParse(t, "_ = 1\n\n_ = 2\n")
+	clone := ast.Clone(chunk).(ast.Chunk)
+
+	for i := range chunk {
+		if chunk[i].Line() != clone[i].Line() {
+			t.Fatalf("Expected line %d, got %d", chunk[i].Line(), clone[i].Line())
+		}
+	}
+}
+
+func TestEqualPositions(t *testing.T) {
+	a := mustParse(t, "_ = f(1, 2)\n")
+	b := mustParse(t, "\n\n_ = f(1,\n2)\n")
+
+	if ast.Equal(a, b, nil) {
+		t.Fatal("Expected trees on different lines to differ")
+	}
+	if !ast.Equal(a, b, &ast.EqualOptions{IgnorePositions: true}) {
+		t.Fatal("Expected trees to be equal when ignoring positions")
+	}
+
+	built := ast.Chunk{&ast.AssignStmt{
+		Lhs: []ast.Expr{&ast.IdentExpr{Value: "_"}},
+		Rhs: []ast.Expr{&ast.FuncCallExpr{
+			Func: &ast.IdentExpr{Value: "f"},
+			Args: []ast.Expr{
+				&ast.NumberExpr{Value: 1, Integer: true, Int: 1},
+				&ast.NumberExpr{Value: 2, Integer: true, Int: 2},
+			},
+		}},
+	}}
+	if !ast.Equal(a, built, &ast.EqualOptions{IgnorePositions: true}) {
+		t.Fatalf("Expected hand built tree to equal parsed one:\n%s", built)
+	}
+	if ast.Equal(a, mustParse(t, "_ = f(1, 3)\n"), &ast.EqualOptions{IgnorePositions: true}) {
+		t.Fatal("Expected trees with different values to differ")
+	}
+	if !ast.Equal(a, mustParse(t, "_ = f(0x1, 0X2)\n"), &ast.EqualOptions{IgnorePositions: true}) {
+		t.Fatal("Expected numbers spelled differently to be equal")
+	}
+	if ast.Equal(a, mustParse(t, "_ = f(1, 2.0)\n"), &ast.EqualOptions{IgnorePositions: true}) {
+		t.Fatal("Expected an integer and a float to differ")
+	}
+	if !ast.Equal(mustParse(t, "_ = 2.0\n"), mustParse(t, "_ = 0.2e1\n"), &ast.EqualOptions{IgnorePositions: true}) {
+		t.Fatal("Expected floats spelled differently to be equal")
+	}
+
+	if !ast.Equal(mustParse(t, "x = 'a'\n"), mustParse(t, "x = \"a\"\n"), &ast.EqualOptions{IgnorePositions: true}) {
+		t.Fatal("Expected strings quoted differently to be equal")
+	}
+
+	str := mustParse(t, "x = [==[a]==]\n")[0].(*ast.AssignStmt).Rhs[0]
+	if !ast.Equal(str, &ast.StringExpr{Value: "a"}, &ast.EqualOptions{IgnorePositions: true}) {
+		t.Fatal("Expected a parsed long string to equal a hand built one")
+	}
+	if ast.Equal(str, &ast.StringExpr{Value: "b"}, &ast.EqualOptions{IgnorePositions: true}) {
+		t.Fatal("Expected strings with different values to differ")
+	}
+
+	parens := []struct {
+		a, b  string
+		equal bool
+	}{
+		{"x = (a + b) * c\n", "x = (a + b) * c\n", true},
+		{"x = ((a)) + (b)\n", "x = a + b\n", true},
+		{"x = (a + b) * c\n", "x = a + b * c\n", false},
+		{"return (f())\n", "return f()\n", false},
+		{"return ((...))\n", "return (...)\n", true},
+	}
+	for _, test := range parens {
+		got := ast.Equal(mustParse(t, test.a), mustParse(t, test.b), &ast.EqualOptions{IgnorePositions: true})
+		if got != test.equal {
+			t.Errorf("Expected Equal(%q, %q) to be %v", test.a, test.b, test.equal)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/notnoobmaster/luautil/ast"
+	"github.com/notnoobmaster/luautil/parse"
+)
+
+var comments = []string{
+	"-- leading\n_ = _;\n",
+	"-- first\n--[[ second ]]\n_ = _;\n",
+	"_ = _; -- trailing\n",
+	"_ = _; --[[ a ]] -- b\n",
+	"-- only a comment\n",
+	"_ = _;\n-- end of chunk\n",
+	"do\n\t-- empty block\nend;\n",
+	"function _()\n\t-- leading\n\treturn _; -- trailing\n\t-- dangling\nend;\n",
+	"if _ then\n\t_ = _;\n\t-- end of then\nelseif _ then\n\t-- elseif\nelse\n\t-- else\nend; -- after if\n",
+	"repeat\n\t-- body\nuntil _;\n",
+	"--[==[ long\ncomment ]==]\n_ = _;\n",
+	"-- doc\nfunction _()\n\t_ = _;\nend;\n",
+	"-- doc\nlocal function _()\n\t-- body\n\t_ = _;\nend;\n",
+	"_ = _; -- trailing\nif _ then\n\t-- then\n\t_ = _;\nend;\n",
+	"-- doc\nwhile _ do\n\tdo\n\t\t-- inner\n\t\t_ = _;\n\tend;\nend;\n",
+}
+
+func TestComments(t *testing.T) {
+	for _, s := range comments {
+		chunk := mustParse(t, s)
+		if chunk.String() != s {
+			t.Fatalf("\nGot:\n%sExpected:\n%s", chunk, s)
+		}
+	}
+}
+
+func TestCommentPlacement(t *testing.T) {
+	src := "x = 1 -- one\n\n-- two\nlocal t = { -- three\n\ta = 1,\n}\n"
+	expected := "x = 1; -- one\n-- two\n-- three\nlocal t = {\n\ta = 1\n};\n"
+
+	chunk := mustParse(t, src)
+	if chunk.String() != expected {
+		t.Fatalf("\nGot:\n%sExpected:\n%s", chunk, expected)
+	}
+	if g := chunk[0].TrailingComments(); g.Text() != "-- one" {
+		t.Fatalf("Expected trailing comment on first statement, got %q", g.Text())
+	}
+	if g := chunk[1].LeadingComments(); g.Text() != "-- two\n-- three" {
+		t.Fatalf("Expected leading comments on second statement, got %q", g.Text())
+	}
+}
+
+func TestFileComments(t *testing.T) {
+	src := "-- one\nx = f(a, --[[ two ]] b) -- three\nif x then\n\t-- four\nend\n-- five\n"
+	file, err := parse.ParseFile(strings.NewReader(src), "test", parse.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	texts := []string{"-- one", "--[[ two ]]", "-- three", "-- four", "-- five"}
+	if len(file.Comments) != len(texts) || file.Name != "test" || len(file.Chunk) != 3 {
+		t.Fatalf("Expected %d comments in 3 statements, got %v in %v", len(texts), file.Comments, file.Chunk)
+	}
+	for i, c := range file.Comments {
+		if c.Text != texts[i] || src[c.Pos().Offset:c.End().Offset] != texts[i] {
+			t.Errorf("Comment %d: expected %q at its position, got %q", i, texts[i], c.Text)
+		}
+	}
+	// The comment inside the call is attached to its statement as well.
+	if g := file.Chunk[0].LeadingComments(); len(g.List) != 2 || g.List[1] != file.Comments[1] {
+		t.Errorf("Expected the comments of the first statement to be shared, got %v", g)
+	}
+
+	if _, err := parse.ParseFile(strings.NewReader("x = -- a\n"), "", parse.Options{}); err == nil {
+		t.Error("Expected a syntax error")
+	}
+}
+
+func TestCommentsEqualClone(t *testing.T) {
+	a := mustParse(t, "-- a\n_ = _ -- b\n-- c\n")
+	b := mustParse(t, "_ = _\n")
+
+	if ast.Equal(a, b, &ast.EqualOptions{IgnorePositions: true}) {
+		t.Fatal("Expected trees with different comments to differ")
+	}
+	if !ast.Equal(a, b, &ast.EqualOptions{IgnorePositions: true, IgnoreComments: true}) {
+		t.Fatal("Expected trees to be equal when ignoring comments")
+	}
+
+	clone := ast.Clone(a).(ast.Chunk)
+	if !ast.Equal(a, clone, nil) {
+		t.Fatalf("Clone differs from original:\n%s", clone)
+	}
+	clone[0].LeadingComments().List[0].Text = "-- changed"
+	if a[0].LeadingComments().List[0].Text != "-- a" {
+		t.Fatal("Modifying the comments of a clone changed the original")
+	}
+}
@@ -0,0 +1,40 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/notnoobmaster/luautil/ast"
+	"github.com/notnoobmaster/luautil/parse"
+)
+
+func TestCompoundDesugar(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{"x += 1", "x = x + 1;\n"},
+		{"x ..= y .. z", "x = x .. y .. z;\n"},
+		{"x *= y + 1", "x = x * (y + 1);\n"},
+		{"a.b -= 1", "a.b = a.b - 1;\n"},
+		{"a[1] //= 2", "a[1] = a[1] // 2;\n"},
+		{"a.b.c ^= 2", "do\n\tlocal __object = a.b;\n\t__object.c = __object.c ^ 2;\nend;\n"},
+		{"a[f()] %= 2", "do\n\tlocal __object, __key = a, f();\n\t__object[__key] = __object[__key] % 2;\nend;\n"},
+		{"a.b[f()] <<= 1", "do\n\tlocal __object, __key = a.b, f();\n\t__object[__key] = __object[__key] << 1;\nend;\n"},
+		{"a.b[f()] += __key", "do\n\tlocal __object, __key1 = a.b, f();\n\t__object[__key1] = __object[__key1] + __key;\nend;\n"},
+		{"__object.b[__key1()] ..= __key .. __object1", "do\n\tlocal __object2, __key2 = __object.b, __key1();\n\t__object2[__key2] = __object2[__key2] .. __key .. __object1;\nend;\n"},
+		{"a[__key] += f(function(__object) return __object end)", "a[__key] = a[__key] + f(function(__object)\n\treturn __object;\nend);\n"},
+	}
+
+	for _, test := range tests {
+		chunk, err := parse.Parse(strings.NewReader(test.src), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		stmt := chunk[0].(*ast.CompoundAssignStmt)
+		got := ast.Chunk{stmt.Desugar()}.String()
+		if got != test.expected {
+			t.Errorf("%q:\nGot:\n%sExpected:\n%s", test.src, got, test.expected)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/notnoobmaster/luautil/ast"
+	"github.com/notnoobmaster/luautil/cst"
+	"github.com/notnoobmaster/luautil/parse"
+)
+
+func TestCSTRoundTrip(t *testing.T) {
+	sources := []string{
+		test,
+		"",
+		"  -- only a comment\n",
+		"local  x = ( 1 + 2 ) ;;\r\nprint 'single' -- trailing\r\n",
+		"--[==[ long\ncomment ]==] local s = [[\nlong string]] .. `a {x} b`\n\n",
+		"f{ a = 1 ; b = 2 , }\nreturn",
+	}
+	for _, src := range sources {
+		f, err := cst.Parse([]byte(src), "", parse.Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(f.Bytes()); got != src {
+			t.Errorf("\nGot:\n%q\nExpected:\n%q", got, src)
+		}
+	}
+}
+
+func TestCSTTokens(t *testing.T) {
+	src := "local x = 1 -- one\n\n-- two\nx = ( x )\n"
+	f, err := cst.Parse([]byte(src), "", parse.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	one := f.Tokens[3]
+	if one.Text != "1" || len(one.Trailing) != 3 || one.Trailing[1].Kind != cst.Comment || one.Trailing[2].Text != "\n" {
+		t.Fatalf("Unexpected trivia after 1: %+v", one.Trailing)
+	}
+	x := f.Tokens[4]
+	if x.Text != "x" || len(x.Leading) != 3 || x.Leading[1].Text != "-- two" {
+		t.Fatalf("Unexpected trivia before x: %+v", x.Leading)
+	}
+
+	assign := f.Root.Children[1]
+	if _, ok := assign.AST.(*ast.AssignStmt); !ok || f.Text(assign) != "x = ( x )" {
+		t.Fatalf("Expected the assignment, got %T %q", assign.AST, f.Text(assign))
+	}
+	rhs := f.Find(assign.AST.(*ast.AssignStmt).Rhs[0])
+	if rhs == nil || f.Text(rhs) != "( x )" || rhs.Parent != assign {
+		t.Fatalf("Expected x with its parentheses, got %+v", rhs)
+	}
+	if n := f.NodeAt(rhs.First); n != rhs {
+		t.Fatalf("Expected NodeAt to return the parentheses, got %+v", n)
+	}
+	if n := f.NodeAt(rhs.First + 1); n.Parent != rhs || f.Text(n) != "x" {
+		t.Fatalf("Expected NodeAt to return x, got %+v", n)
+	}
+}
+
+func TestCSTEdit(t *testing.T) {
+	src := "local count = 0 -- start\nfor i = 1, 10 do\n    count = count + i;\nend\nprint( count , 'done' )\n"
+	f, err := cst.Parse([]byte(src), "", parse.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Rename count to total.
+	for _, tok := range f.Tokens {
+		if tok.Type == parse.TIdent && tok.Text == "count" {
+			tok.Text = "total"
+		}
+	}
+	expected := "local total = 0 -- start\nfor i = 1, 10 do\n    total = total + i;\nend\nprint( total , 'done' )\n"
+	if got := string(f.Bytes()); got != expected {
+		t.Fatalf("\nGot:\n%s\nExpected:\n%s", got, expected)
+	}
+
+	call := f.Root.Children[2].AST.(*ast.FuncCallStmt).Expr.(*ast.FuncCallExpr)
+	f.Replace(f.Find(call.Args[1]), `"finished"`)
+	expected = "local total = 0 -- start\nfor i = 1, 10 do\n    total = total + i;\nend\nprint( total , \"finished\" )\n"
+	if got := string(f.Bytes()); got != expected {
+		t.Fatalf("\nGot:\n%s\nExpected:\n%s", got, expected)
+	}
+
+	chunk, err := f.AST()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := chunk[0].(*ast.LocalAssignStmt).Names[0]; name != "total" {
+		t.Fatalf("Expected the edited tree, got %q", name)
+	}
+}
+
+func TestCSTDialect(t *testing.T) {
+	f, err := cst.Parse([]byte("goto = 1\n"), "", parse.Options{Dialect: parse.Lua51})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok := f.Tokens[0]; tok.Text != "goto" || parse.KindOf(tok.Type) != parse.KindIdent {
+		t.Fatalf("Expected goto to be a name in Lua 5.1, got %v %q", parse.KindOf(tok.Type), tok.Text)
+	}
+}
@@ -0,0 +1,131 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/notnoobmaster/luautil/ast"
+	"github.com/notnoobmaster/luautil/parse"
+)
+
+func TestDiagnostics(t *testing.T) {
+	tests := []struct {
+		src     string
+		dialect parse.Dialect
+		code    string
+		fixed   string // src with the fix applied, if any
+	}{
+		{"if a then x = 1", parse.AnyDialect, parse.CodeUnclosed, "if a then x = 1 end"},
+		{"f(a, b\nx = 1", parse.AnyDialect, parse.CodeUnclosed, "f(a, b)\nx = 1"},
+		{"f()\n(g)()", parse.AnyDialect, parse.CodeAmbiguous, "f()\n;(g)()"},
+		{"x = = 1", parse.AnyDialect, parse.CodeSyntax, ""},
+		{"x = 'abc", parse.AnyDialect, parse.CodeToken, ""},
+		{"x = 0b101", parse.Lua53, parse.CodeUnsupported, ""},
+		{"goto done", parse.Lua51, parse.CodeSyntax, ""},
+		{"x = a // b", parse.Lua51, parse.CodeUnsupported, ""},
+		{"local x <const> = 1; x = 2", parse.Lua54, parse.CodeConst, ""},
+	}
+	for _, test := range tests {
+		_, err := parse.ParseWithOptions(strings.NewReader(test.src), "", parse.Options{Dialect: test.dialect})
+		diagnostics := parse.Diagnostics(err)
+		if len(diagnostics) != 1 {
+			t.Errorf("Expected a diagnostic for %q, got %v", test.src, err)
+			continue
+		}
+		d := diagnostics[0]
+		if d.Code != test.code || d.Severity != parse.SeverityError {
+			t.Errorf("Expected a %s error for %q, got a %s %s: %s", test.code, test.src, d.Severity, d.Code, d.Message)
+		}
+		if d.Pos.Line > 0 && (d.End.Line < d.Pos.Line || d.End.Line == d.Pos.Line && d.End.Column < d.Pos.Column) {
+			t.Errorf("Expected %v to end after %v for %q", d.End, d.Pos, test.src)
+		}
+		if test.fixed == "" {
+			if len(d.Fixes) != 0 {
+				t.Errorf("Unexpected fixes for %q: %v", test.src, d.Fixes)
+			}
+			continue
+		}
+		if len(d.Fixes) != 1 {
+			t.Errorf("Expected a fix for %q, got %v", test.src, d.Fixes)
+			continue
+		}
+		fix := d.Fixes[0]
+		fixed := test.src[:fix.Pos.Offset] + fix.NewText + test.src[fix.End.Offset:]
+		if fixed != test.fixed {
+			t.Errorf("Expected the fix to give %q, got %q", test.fixed, fixed)
+		}
+		if _, err := parse.Parse(strings.NewReader(fixed), ""); err != nil {
+			t.Errorf("Expected %q to parse: %v", fixed, err)
+		}
+	}
+}
+
+func TestDiagnosticsList(t *testing.T) {
+	_, err := parse.ParseAll(strings.NewReader("x = = 1\ny = 'a\nif z then"), "")
+	diagnostics := parse.Diagnostics(err)
+	codes := []string{parse.CodeSyntax, parse.CodeToken, parse.CodeUnclosed}
+	if len(diagnostics) != len(codes) {
+		t.Fatalf("Expected %d diagnostics, got %v", len(codes), err)
+	}
+	for i, d := range diagnostics {
+		if d.Code != codes[i] || d.Message != err.(parse.ErrorList)[i].Message {
+			t.Errorf("Diagnostic %d: expected a %s error, got %s: %s", i, codes[i], d.Code, d.Message)
+		}
+	}
+
+	_, err = parse.ParseWithOptions(strings.NewReader("x = 1"), "", parse.Options{MaxTokens: 1})
+	if d := parse.Diagnostics(err); len(d) != 1 || d[0].Code != parse.CodeLimit {
+		t.Fatalf("Expected a limit diagnostic, got %v", d)
+	}
+	if parse.Diagnostics(nil) != nil {
+		t.Fatal("Expected no diagnostics without an error")
+	}
+}
+
+func TestDiagnosticPositions(t *testing.T) {
+	tests := []struct {
+		src      string
+		pos, end ast.Position
+	}{
+		{"x = \"abc\ny = 1", ast.Position{Line: 1, Column: 5, Offset: 4}, ast.Position{Line: 2, Column: 1, Offset: 9}},
+		{"x = 'abc", ast.Position{Line: 1, Column: 5, Offset: 4}, ast.Position{Line: 1, Column: 9, Offset: 8}},
+		{"x = 1 @", ast.Position{Line: 1, Column: 7, Offset: 6}, ast.Position{Line: 1, Column: 8, Offset: 7}},
+		{"while true do\nx()\n", ast.Position{Line: 3, Column: 1, Offset: 18}, ast.Position{Line: 3, Column: 1, Offset: 18}},
+		{"x = -", ast.Position{Line: 1, Column: 6, Offset: 5}, ast.Position{Line: 1, Column: 6, Offset: 5}},
+		{"--[[ open", ast.Position{Line: 1, Column: 1, Offset: 0}, ast.Position{Line: 1, Column: 10, Offset: 9}},
+	}
+	for _, test := range tests {
+		_, err := parse.Parse(strings.NewReader(test.src), "")
+		d := parse.Diagnostics(err)
+		if len(d) != 1 {
+			t.Errorf("Expected a diagnostic for %q, got %v", test.src, err)
+			continue
+		}
+		if d[0].Pos != test.pos || d[0].End != test.end {
+			t.Errorf("Expected %q to be reported from %v to %v, got %v to %v", test.src, test.pos, test.end, d[0].Pos, d[0].End)
+		}
+	}
+}
+
+func TestMalformedLiterals(t *testing.T) {
+	literals := []string{
+		"0b" + strings.Repeat("1", 100), "0o" + strings.Repeat("7", 40), "0x" + strings.Repeat("f", 40),
+		strings.Repeat("9", 400), "1e99999", "0x1p99999", "0b", "0o", "0x", "0b2", "0o8", "1e", "1e+",
+		"0x.p1", "1__", "0b_", "'\\x'", "'\\u{110000000}'", "'\\u{'", "'\\999'", "[==[", "`{", "`{{`", "'",
+	}
+	for _, literal := range literals {
+		src := "x = " + literal + "\ny = " + literal
+		for _, dialect := range []parse.Dialect{parse.AnyDialect, parse.Lua51, parse.Luau} {
+			func() {
+				defer func() {
+					if e := recover(); e != nil {
+						t.Errorf("Parsing %q in %v panicked: %v", src, dialect, e)
+					}
+				}()
+				parse.ParseWithOptions(strings.NewReader(src), "", parse.Options{Dialect: dialect})
+				parse.ParseAll(strings.NewReader(src), "")
+				parse.Tokenize(strings.NewReader(src), "")
+			}()
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/notnoobmaster/luautil/ast"
+	"github.com/notnoobmaster/luautil/parse"
+)
+
+func TestDialects(t *testing.T) {
+	all := []parse.Dialect{parse.Lua51, parse.Lua52, parse.Lua53, parse.Lua54, parse.Luau}
+	tests := []struct {
+		src      string
+		accepted []parse.Dialect
+	}{
+		{"x = 1 + 2", all},
+		{"goto done ::done::", []parse.Dialect{parse.Lua52, parse.Lua53, parse.Lua54}},
+		{"::top::", []parse.Dialect{parse.Lua52, parse.Lua53, parse.Lua54}},
+		{"local goto = 1", []parse.Dialect{parse.Lua51, parse.Luau}},
+		{"while true do continue end", []parse.Dialect{parse.Luau}},
+		{"local continue = 1", all},
+		{"continue = 1; continue, x = 2, 3; t.continue = continue", all},
+		{"continue(); continue 'a'; continue {}; continue:f(); continue.x = 1; continue[1] = 2", all},
+		{"while true do local continue = f; continue() continue end", []parse.Dialect{parse.Luau}},
+		{"continue += 1", []parse.Dialect{parse.Luau}},
+		{"x += 1", []parse.Dialect{parse.Luau}},
+		{"x ..= 'a'", []parse.Dialect{parse.Luau}},
+		{"x //= 2", []parse.Dialect{parse.Luau}},
+		{"x &= 1", nil},
+		{"x <<= 1", nil},
+		{"x = 7 // 2", []parse.Dialect{parse.Lua53, parse.Lua54, parse.Luau}},
+		{"x = 1 << 2 | 3 & ~4", []parse.Dialect{parse.Lua53, parse.Lua54}},
+		{"x = 1 ~ 2", []parse.Dialect{parse.Lua53, parse.Lua54}},
+		{`x = "\x41"`, []parse.Dialect{parse.Lua52, parse.Lua53, parse.Lua54, parse.Luau}},
+		{`x = "a\z   b"`, []parse.Dialect{parse.Lua52, parse.Lua53, parse.Lua54, parse.Luau}},
+		{`x = "\u{48}"`, []parse.Dialect{parse.Lua53, parse.Lua54, parse.Luau}},
+		{`x = "\u{10FFFF}"`, []parse.Dialect{parse.Lua53, parse.Lua54, parse.Luau}},
+		{`x = "\u{110000}"`, []parse.Dialect{parse.Lua54}},
+		{`x = "\u{7FFFFFFF}"`, []parse.Dialect{parse.Lua54}},
+		{`x = "\u{80000000}"`, nil},
+		{"f()\n(g)()", []parse.Dialect{parse.Lua52, parse.Lua53, parse.Lua54}},
+		{`x = "\65\n"`, all},
+		{"x = 0xff", all},
+		{"x = 0b101", []parse.Dialect{parse.Luau}},
+		{"x = 1_000", []parse.Dialect{parse.Luau}},
+		{"x = 0o17", nil},
+		{"x = 0x1p4", []parse.Dialect{parse.Lua52, parse.Lua53, parse.Lua54}},
+		{"x = 0xA.8", []parse.Dialect{parse.Lua52, parse.Lua53, parse.Lua54}},
+		{"x = if a then b else c", []parse.Dialect{parse.Luau}},
+		{"x = if a then b", nil},
+		{"x = `a {b} c`", []parse.Dialect{parse.Luau}},
+	}
+
+	for _, test := range tests {
+		for _, dialect := range all {
+			_, err := parse.ParseWithOptions(strings.NewReader(test.src), "", parse.Options{Dialect: dialect})
+			accepted := false
+			for _, d := range test.accepted {
+				accepted = accepted || d == dialect
+			}
+			if accepted && err != nil {
+				t.Errorf("Expected %s to accept %q, got %v", dialect, test.src, err)
+			} else if !accepted && err == nil {
+				t.Errorf("Expected %s to reject %q", dialect, test.src)
+			}
+		}
+	}
+}
+
+func TestUTF8Escapes(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{`"\u{48}"`, "H"},
+		{`"\u{0000E9}"`, "\u00e9"},
+		{`"\u{D800}"`, "\xed\xa0\x80"},
+		{`"\u{10FFFF}"`, "\xf4\x8f\xbf\xbf"},
+		{`"\u{200000}"`, "\xf8\x88\x80\x80\x80"},
+		{`"\u{7FFFFFFF}"`, "\xfd\xbf\xbf\xbf\xbf\xbf"},
+	}
+
+	for _, test := range tests {
+		chunk, err := parse.ParseWithOptions(strings.NewReader("x = "+test.src), "", parse.Options{Dialect: parse.Lua54})
+		if err != nil {
+			t.Fatalf("%s: %v", test.src, err)
+		}
+		if got := chunk[0].(*ast.AssignStmt).Rhs[0].(*ast.StringExpr).Value; got != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.src, test.expected, got)
+		}
+	}
+}
+
+func TestDialectErrors(t *testing.T) {
+	tests := []struct {
+		src     string
+		dialect parse.Dialect
+		message string
+	}{
+		{"x = 1 & 2", parse.Lua52, "bitwise operators are not supported in Lua 5.2"},
+		{"x = ~1", parse.Luau, "bitwise operators are not supported in Luau"},
+		{"x = 1 // 2", parse.Lua51, "floor division is not supported in Lua 5.1"},
+		{"x -= 1", parse.Lua54, "compound assignments are not supported in Lua 5.4"},
+		{"::a::", parse.Luau, "labels are not supported in Luau"},
+		{`x = "\u{48}"`, parse.Lua52, `escape sequence '\u' is not supported in Lua 5.2`},
+		{"x = 0b1", parse.Lua53, "binary numbers are not supported in Lua 5.3"},
+		{"x = 1_0", parse.Lua54, "digit separators are not supported in Lua 5.4"},
+		{"x = 0x1.8", parse.Lua51, "hexadecimal floats are not supported in Lua 5.1"},
+		{"x = 1e", parse.Lua53, "malformed number"},
+		{"a, b += 1", parse.Luau, "compound assignment takes a single target"},
+		{"x |= 1", parse.Luau, "bitwise compound assignments are not supported in Luau"},
+		{"x = `a`", parse.Lua54, "interpolated strings are not supported in Lua 5.4"},
+		{"x = `a{{b}}`", parse.Luau, `double braces are not permitted within interpolated strings, did you mean '\{'?`},
+		{"x = `a{b", parse.Luau, "'}' expected"},
+		{"x = `a{b}\n`", parse.Luau, "unterminated interpolated string"},
+	}
+
+	for _, test := range tests {
+		_, err := parse.ParseWithOptions(strings.NewReader(test.src), "", parse.Options{Dialect: test.dialect})
+		e, ok := err.(*parse.Error)
+		if !ok || e.Message != test.message {
+			t.Errorf("Expected %q for %q in %s, got %v", test.message, test.src, test.dialect, err)
+		}
+	}
+}
+
+func TestContextualContinue(t *testing.T) {
+	src := "while x do\nlocal continue = 1\ncontinue = 2\ncontinue()\ncontinue\nend"
+	chunk, err := parse.ParseWithOptions(strings.NewReader(src), "", parse.Options{Dialect: parse.Luau})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := chunk[0].(*ast.WhileStmt).Chunk
+	if len(body) != 4 {
+		t.Fatalf("Expected 4 statements, got %v", body)
+	}
+	if _, ok := body[1].(*ast.AssignStmt); !ok {
+		t.Errorf("Expected an assignment to continue, got %T", body[1])
+	}
+	if _, ok := body[2].(*ast.FuncCallStmt); !ok {
+		t.Errorf("Expected a call of continue, got %T", body[2])
+	}
+	if _, ok := body[3].(*ast.ContinueStmt); !ok {
+		t.Errorf("Expected a continue statement, got %T", body[3])
+	}
+}
+
+func TestLocalAttribs(t *testing.T) {
+	lua54 := parse.Options{Dialect: parse.Lua54}
+	src := "local x <const>, y, f <close> = 1, 2, io.open('f')\n"
+	chunk, err := parse.ParseWithOptions(strings.NewReader(src), "", lua54)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmt := chunk[0].(*ast.LocalAssignStmt)
+	if got := strings.Join(stmt.Attribs, ","); got != "const,,close" {
+		t.Fatalf("Expected attributes const,,close, got %q", got)
+	}
+	expected := "local x <const>, y, f <close> = 1, 2, io.open(\"f\");\n"
+	if got := chunk.String(); got != expected {
+		t.Fatalf("\nGot:\n%sExpected:\n%s", got, expected)
+	}
+
+	valid := []string{
+		"local x <const> = 1\ndo local x = 2; x = 3 end",
+		"local x <const> = 1\nlocal function f(x) x = 2 end",
+		"local x <const> = 1\nfor x = 1, 2 do x = 3 end",
+		"local self <const> = 1\nfunction t:m() self = 2 end",
+		"local a <close>, b = f()",
+		"local t <const> = {}; function t.f() end function t:g() end",
+	}
+	for _, src := range valid {
+		if _, err := parse.ParseWithOptions(strings.NewReader(src), "", lua54); err != nil {
+			t.Errorf("Expected %q to parse, got %v", src, err)
+		}
+	}
+
+	invalid := []struct {
+		src     string
+		message string
+	}{
+		{"local x <const> = 1\nx = 2", "attempt to assign to const variable 'x'"},
+		{"local x <const> = 1\nfunction f() y, x = 1, 2 end", "attempt to assign to const variable 'x'"},
+		{"local x <const> = 1; function x() end", "attempt to assign to const variable 'x'"},
+		{"local a <close>, b <close> = f()", "multiple to-be-closed variables in local list"},
+		{"local a <static> = 1", "unknown attribute 'static'"},
+	}
+	for _, test := range invalid {
+		_, err := parse.ParseWithOptions(strings.NewReader(test.src), "", lua54)
+		if e, ok := err.(*parse.Error); !ok || e.Message != test.message {
+			t.Errorf("Expected %q for %q, got %v", test.message, test.src, err)
+		}
+	}
+
+	_, err = parse.ParseWithOptions(strings.NewReader("local x <const> = 1"), "", parse.Options{Dialect: parse.Lua53})
+	if e, ok := err.(*parse.Error); !ok || e.Message != "attributes are not supported in Lua 5.3" {
+		t.Errorf("Expected attributes to be rejected in Lua 5.3, got %v", err)
+	}
+}
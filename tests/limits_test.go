@@ -0,0 +1,69 @@
+package tests
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/notnoobmaster/luautil/parse"
+)
+
+func TestParseLimits(t *testing.T) {
+	deep := "x = " + strings.Repeat("(", 1000) + "1" + strings.Repeat(")", 1000)
+	tests := []struct {
+		src   string
+		opts  parse.Options
+		limit string
+		line  int
+	}{
+		{deep, parse.Options{MaxDepth: 200}, "MaxDepth", 1},
+		{strings.Repeat("do ", 300) + strings.Repeat("end ", 300), parse.Options{MaxDepth: 200}, "MaxDepth", 1},
+		{"x = 1\ny = " + strings.Repeat("not ", 300) + "1", parse.Options{MaxDepth: 200}, "MaxDepth", 2},
+		{"x = 1\ny = 2\nz = 'abc'", parse.Options{MaxBytes: 15}, "MaxBytes", 3},
+		{"x = 1\ny = {1, 2, 3}", parse.Options{MaxTokens: 8}, "MaxTokens", 2},
+		{"x = 1", parse.Options{MaxBytes: 5, MaxTokens: 3, MaxDepth: 2}, "", 0},
+		{deep, parse.Options{}, "MaxDepth", 1},
+		{deep, parse.Options{MaxDepth: -1}, "", 0},
+		{"x = " + strings.Repeat("(", 400) + "1" + strings.Repeat(")", 400), parse.Options{}, "", 0},
+	}
+	for _, test := range tests {
+		_, err := parse.ParseWithOptions(strings.NewReader(test.src), "", test.opts)
+		if test.limit == "" {
+			if err != nil {
+				t.Errorf("Unexpected error for %.20q: %v", test.src, err)
+			}
+			continue
+		}
+		e, ok := err.(*parse.LimitError)
+		if !ok {
+			t.Errorf("Expected a *parse.LimitError for %.20q, got %v", test.src, err)
+			continue
+		}
+		if e.Limit != test.limit || e.Pos.Line != test.line {
+			t.Errorf("Expected %s exceeded at line %d for %.20q, got %s at line %d", test.limit, test.line, test.src, e.Limit, e.Pos.Line)
+		}
+	}
+}
+
+func TestDefaultMaxDepth(t *testing.T) {
+	src := strings.Repeat("x = {", 100000)
+	_, err := parse.Parse(strings.NewReader(src), "")
+	if e, ok := err.(*parse.LimitError); !ok || e.Limit != "MaxDepth" || e.Max != parse.DefaultMaxDepth {
+		t.Errorf("Expected the default MaxDepth to be exceeded, got %v", err)
+	}
+	_, err = parse.ParseAll(strings.NewReader(src), "")
+	if e, ok := err.(*parse.LimitError); !ok || e.Limit != "MaxDepth" {
+		t.Errorf("Expected ParseAll to stop at the default MaxDepth, got %v", err)
+	}
+}
+
+func TestParseContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, err := parse.ParseContext(ctx, strings.NewReader(test), "", parse.Options{}); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if _, err := parse.ParseContext(ctx, strings.NewReader(test), "", parse.Options{}); err != context.Canceled {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+}
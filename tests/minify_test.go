@@ -0,0 +1,92 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/notnoobmaster/luautil/ast"
+	"github.com/notnoobmaster/luautil/parse"
+)
+
+func TestPrintCompact(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{"local x = 1\nlocal y = x + 1", "local x=1 local y=x+1"},
+		{"x = a - (-b)", "x=a-(-b)"},
+		{"x = 1 .. y .. 2 .. .5", "x=1 ..y..2 .. .5"},
+		{"x = ... .. a", "x=... ..a"},
+		{"local x <const> = 1", "local x<const> =1"},
+		{"a = b; (f or g)() h()", "a=b;(f or g)()h()"},
+		{"x = 'a b' -- comment\n--[[ block ]] y = 2", `x="a b"y=2`},
+		{"if x then return end", "if x then return end"},
+		{"t = {1, 2, k = {}}", "t={1,2,k={}}"},
+		{"f(function(a, b) return a end)", "f(function(a,b)return a end)"},
+		{"x = (a:d(e)):f(g)", "x=a:d(e):f(g)"},
+		{"x = (a.b(c)).d[e](f)", "x=a.b(c).d[e](f)"},
+		{"x = (f())()", "x=f()()"},
+		{"x = (...).y", "x=(...).y"},
+	}
+
+	for _, test := range tests {
+		chunk, err := parse.Parse(strings.NewReader(test.src), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ast.PrintCompact(chunk); got != test.expected {
+			t.Errorf("%q:\nGot:      %s\nExpected: %s", test.src, got, test.expected)
+		}
+	}
+}
+
+func TestMinifyRename(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{"local value = 1 print(value)", "local a=1 print(a)"},
+		{"local a, b = 1, 2 local function f(x, y) return x + y + a end", "local a,b=1,2 local function c(d,e)return d+e+a end"},
+		{"local x = 1 do local y = x end local z = 2", "local a=1 do local b=a end local b=2"},
+		{"local x = 1 local x = x + 1", "local a=1 local b=a+1"},
+		{"for i = 1, 2 do local i = i end", "for a=1,2 do local b=a end"},
+		{"repeat local done = f() until done", "repeat local a=f()until a"},
+		{"function t:m(x) return self, x end", "function t:m(a)return self,a end"},
+		{"local t = {a = 1} print(t.a, t['b'])", "local a={a=1}print(a.a,a.b)"},
+		{"local f = function() return f end", "local a=function()return f end"},
+		{"a = 1 local b = function() return a end", "a=1 local b=function()return a end"},
+		{"local x = 1 print(`{x}`)", "local a=1 print(`{a}`)"},
+		{"local _ENV = {x = 1} print(x)", "local _ENV={x=1}print(x)"},
+	}
+
+	for _, test := range tests {
+		chunk, err := parse.Parse(strings.NewReader(test.src), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		original := chunk.String()
+		if got := ast.Minify(chunk, ast.MinifyOptions{RenameLocals: true}); got != test.expected {
+			t.Errorf("%q:\nGot:      %s\nExpected: %s", test.src, got, test.expected)
+		}
+		if chunk.String() != original {
+			t.Errorf("%q: Minify modified the chunk", test.src)
+		}
+	}
+}
+
+func TestMinifyRoundTrip(t *testing.T) {
+	chunk, err := parse.Parse(strings.NewReader(test), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, options := range []ast.MinifyOptions{{}, {RenameLocals: true}} {
+		minified := ast.Minify(chunk, options)
+		reparsed, err := parse.Parse(strings.NewReader(minified), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ast.Minify(reparsed, options); got != minified {
+			t.Errorf("\nGot:\n%s\nExpected:\n%s", got, minified)
+		}
+	}
+}
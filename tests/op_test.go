@@ -0,0 +1,50 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/notnoobmaster/luautil/ast"
+)
+
+func TestOpFormat(t *testing.T) {
+	a, b, c := &ast.IdentExpr{Value: "a"}, &ast.IdentExpr{Value: "b"}, &ast.IdentExpr{Value: "c"}
+	tests := []struct {
+		expr     ast.Expr
+		expected string
+	}{
+		{&ast.ArithmeticOpExpr{Operator: ast.OpMul, Lhs: &ast.ArithmeticOpExpr{Operator: ast.OpAdd, Lhs: a, Rhs: b}, Rhs: c}, "(a + b) * c"},
+		{&ast.ArithmeticOpExpr{Operator: ast.OpPow, Lhs: a, Rhs: &ast.ArithmeticOpExpr{Operator: ast.OpPow, Lhs: b, Rhs: c}}, "a ^ b ^ c"},
+		{&ast.ArithmeticOpExpr{Operator: ast.OpPow, Lhs: &ast.ArithmeticOpExpr{Operator: ast.OpPow, Lhs: a, Rhs: b}, Rhs: c}, "(a ^ b) ^ c"},
+		{&ast.LogicalOpExpr{Operator: ast.OpAnd, Lhs: &ast.LogicalOpExpr{Operator: ast.OpOr, Lhs: a, Rhs: b}, Rhs: c}, "(a or b) and c"},
+		{&ast.RelationalOpExpr{Operator: ast.OpLe, Lhs: a, Rhs: &ast.ArithmeticOpExpr{Operator: ast.OpShl, Lhs: b, Rhs: c}}, "a <= b << c"},
+		{&ast.UnaryOpExpr{Operator: ast.OpNot, Expr: a}, "not a"},
+		{&ast.UnaryOpExpr{Operator: ast.OpBnot, Expr: &ast.UnaryOpExpr{Operator: ast.OpLen, Expr: a}}, "~(#a)"},
+		{&ast.UnaryOpExpr{Operator: ast.OpNeg, Expr: &ast.UnaryOpExpr{Operator: ast.OpNeg, Expr: a}}, "-(-a)"},
+		{&ast.ArithmeticOpExpr{Operator: ast.OpMul, Lhs: &ast.UnaryOpExpr{Operator: ast.OpNeg, Expr: a}, Rhs: b}, "-a * b"},
+		{&ast.ArithmeticOpExpr{Operator: ast.OpPow, Lhs: &ast.UnaryOpExpr{Operator: ast.OpNeg, Expr: a}, Rhs: b}, "(-a) ^ b"},
+		{&ast.UnaryOpExpr{Operator: ast.OpNeg, Expr: &ast.ArithmeticOpExpr{Operator: ast.OpPow, Lhs: a, Rhs: b}}, "-a ^ b"},
+	}
+	for _, test := range tests {
+		if got := test.expr.String(); got != test.expected {
+			t.Errorf("Expected %q, got %q", test.expected, got)
+		}
+	}
+}
+
+func TestOpMethods(t *testing.T) {
+	if !ast.OpPow.RightAssoc() || !ast.OpConcat.RightAssoc() || ast.OpAdd.RightAssoc() {
+		t.Error("Expected only ^ and .. to be right associative")
+	}
+	if !ast.OpNeg.IsUnary() || ast.OpSub.IsUnary() {
+		t.Error("Expected - to be unary only as OpNeg")
+	}
+	if !ast.OpGe.IsComparison() || ast.OpAnd.IsComparison() {
+		t.Error("Expected >= to be a comparison and not and")
+	}
+	if ast.OpMul.Precedence() <= ast.OpAdd.Precedence() || ast.OpPow.Precedence() <= ast.OpNeg.Precedence() {
+		t.Error("Unexpected precedences")
+	}
+	if got := ast.Op(0).String(); got != "Op(0)" {
+		t.Errorf("Expected Op(0), got %q", got)
+	}
+}
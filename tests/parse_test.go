@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/notnoobmaster/luautil/parse"
+)
+
+const benchSrc = `local Queue = {}
+Queue.__index = Queue
+
+function Queue.new(...)
+	local self = setmetatable({first = 1, last = 0, items = {}}, Queue)
+	for i, v in ipairs({...}) do
+		self:push(v)
+	end
+	return self
+end
+
+function Queue:push(v)
+	self.last = self.last + 1
+	self.items[self.last] = v
+end
+
+function Queue:pop()
+	if self.first > self.last then
+		return nil, "queue is empty"
+	elseif self.items[self.first] == nil then
+		error(("hole at %d"):format(self.first))
+	end
+	local v = self.items[self.first]
+	self.items[self.first] = nil
+	self.first = self.first + 1
+	return v
+end
+
+local function fib(n)
+	if n < 2 then return n end
+	return fib(n - 1) + fib(n - 2)
+end
+
+local t = {1, 2, 3; x = -1.5e3, ["y"] = not true, [fib(3)] = #"abc" .. 'def'}
+while t.x < 0 and (t.y or t[1] ~= 2) do
+	t.x = t.x + 2 ^ 3 % 7 // 2
+	repeat t[#t + 1] = t.x until #t >= 10
+end
+`
+
+func BenchmarkParse(b *testing.B) {
+	src := strings.Repeat(benchSrc, 20)
+	b.SetBytes(int64(len(src)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := parse.Parse(strings.NewReader(src), ""); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		src     string
+		line    int
+		token   string
+		message string
+	}{
+		{"if a b then end", 1, "b", "'then' expected"},
+		{"x = = 1", 1, "=", "unexpected symbol"},
+		{"x\ny = 1", 2, "y", "syntax error"},
+		{"f() = 1", 1, "=", "syntax error"},
+		{"local 1 = 2", 1, "1", "<name> expected"},
+		{"for i do end", 1, "do", "'=' or 'in' expected"},
+		{"function f(a,) end", 1, ")", "<name> expected"},
+		{"t = {1, 2\nx = 3", 2, "x", "'}' expected (to close '{' at line 1)"},
+		{"while true do\nx()\n", 3, "", "'end' expected (to close 'while' at line 1)"},
+		{"function f()\nreturn 1 x = 2\nend", 2, "x", "'end' expected (to close 'function' at line 1)"},
+		{"end", 1, "end", "'<eof>' expected"},
+		{"f()\n(g)()", 2, "(", "ambiguous syntax (function call x new statement)"},
+		{"(f()) x = 1", 1, "x", "syntax error"},
+	}
+
+	for _, test := range tests {
+		_, err := parse.Parse(strings.NewReader(test.src), "")
+		e, ok := err.(*parse.Error)
+		if !ok {
+			t.Fatalf("Expected a *parse.Error for %q, got %v", test.src, err)
+		}
+		if e.Pos.Line != test.line || e.Token != test.token || e.Message != test.message {
+			t.Fatalf("Expected %q near %q at line %d for %q, got %q near %q at line %d",
+				test.message, test.token, test.line, test.src, e.Message, e.Token, e.Pos.Line)
+		}
+	}
+}
+
+func TestParsePrecedence(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{"x = 1 + 2 * 3", "x = 1 + 2 * 3;\n"},
+		{"x = (1 + 2) * 3", "x = (1 + 2) * 3;\n"},
+		{"x = a .. b .. c", "x = a .. b .. c;\n"},
+		{"x = (a .. b) .. c", "x = (a .. b) .. c;\n"},
+		{"x = 2 ^ 3 ^ 2", "x = 2 ^ 3 ^ 2;\n"},
+		{"x = -2 ^ 2", "x = -2 ^ 2;\n"},
+		{"x = (-2) ^ 2", "x = (-2) ^ 2;\n"},
+		{"x = a == b | c", "x = a == b | c;\n"},
+		{"x = (a == b) | c", "x = (a == b) | c;\n"},
+		{"x = not a == b", "x = not a == b;\n"},
+		{"x = a or b and c", "x = a or b and c;\n"},
+		{"x = (a or b) and c", "x = (a or b) and c;\n"},
+	}
+
+	for _, test := range tests {
+		chunk := mustParse(t, test.src)
+		if got := chunk.String(); got != test.expected {
+			t.Fatalf("Parsing %q\nGot:\n%sExpected:\n%s", test.src, got, test.expected)
+		}
+	}
+}
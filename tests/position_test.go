@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/notnoobmaster/luautil/ast"
+)
+
+const positionSrc = `local t = {a = 1, [2] = "x"}
+function t.f(a, ...) return a + #t end
+if a then
+	print(t:f "s")
+elseif b then
+else
+end
+`
+
+func TestPositions(t *testing.T) {
+	chunk := mustParse(t, positionSrc)
+
+	text := func(n ast.PositionHolder) string {
+		return positionSrc[n.Pos().Offset:n.End().Offset]
+	}
+
+	expected := map[string]bool{
+		`local t = {a = 1, [2] = "x"}`:           true,
+		`{a = 1, [2] = "x"}`:                     true,
+		`a = 1`:                                  true,
+		`[2] = "x"`:                              true,
+		`function t.f(a, ...) return a + #t end`: true,
+		`t.f`:                                    true,
+		`a, ...`:                                 true,
+		`return a + #t`:                          true,
+		`#t`:                                     true,
+		`t:f "s"`:                                true,
+		"elseif b then\nelse\nend":               true,
+	}
+
+	ast.Inspect(chunk, func(n ast.Node) bool {
+		p, ok := n.(ast.PositionHolder)
+		if !ok {
+			return true
+		}
+		pos, end := p.Pos(), p.End()
+		if pos.Line < 1 || pos.Column < 1 || end.Offset < pos.Offset {
+			t.Fatalf("%T has invalid span %v to %v", n, pos, end)
+		}
+		if pos.Line != p.Line() || end.Line != p.LastLine() {
+			t.Fatalf("%T lines %d-%d differ from span %v to %v", n, p.Line(), p.LastLine(), pos, end)
+		}
+		delete(expected, text(p))
+		return true
+	})
+
+	for s := range expected {
+		t.Errorf("No node spans %q", s)
+	}
+
+	call := chunk[2].(*ast.IfStmt).Then[0].(*ast.FuncCallStmt)
+	if pos := call.Pos(); pos.Line != 4 || pos.Column != 2 {
+		t.Fatalf("Expected call at 4:2, got %d:%d", pos.Line, pos.Column)
+	}
+}
+
+func TestFunctionPositions(t *testing.T) {
+	src := "function f() end\nlocal function g() end\nh = function() end\n"
+	chunk := mustParse(t, src)
+
+	var got []string
+	ast.Inspect(chunk, func(n ast.Node) bool {
+		if f, ok := n.(*ast.FunctionExpr); ok {
+			got = append(got, src[f.Pos().Offset:f.End().Offset])
+		}
+		return true
+	})
+
+	expected := []string{"function f() end", "function g() end", "function() end"}
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %d functions, got %q", len(expected), got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("Expected function %d to span %q, got %q", i, expected[i], got[i])
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package tests
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/notnoobmaster/luautil/ast"
+	"github.com/notnoobmaster/luautil/parse"
+)
+
+func TestFprint(t *testing.T) {
+	tests := []struct {
+		src      string
+		config   ast.PrintConfig
+		expected string
+	}{
+		{"if a then b() end", ast.PrintConfig{Indent: 2}, "if a then\n  b();\nend;\n"},
+		{"a() b()", ast.PrintConfig{Semicolons: ast.SemicolonsNever}, "a()\nb()\n"},
+		{"a = b; (f or g)() c()", ast.PrintConfig{Semicolons: ast.SemicolonsRequired}, "a = b;\n(f or g)()\nc()\n"},
+		{"a = b; -- x\n(f or g).x = 1", ast.PrintConfig{Semicolons: ast.SemicolonsRequired}, "a = b; -- x\n(f or g).x = 1\n"},
+		{`x = "a", 'b"', "c'"`, ast.PrintConfig{Quote: '\''}, "x = 'a', 'b\"', \"c'\";\n"},
+		{`x = 'a', "b'"`, ast.PrintConfig{Quote: '"'}, "x = \"a\", \"b'\";\n"},
+		{`require("x") f({1}) g(1)`, ast.PrintConfig{OmitCallParens: true}, "require \"x\";\nf {\n\t1\n};\ng(1);\n"},
+		{"x = {1, {a = 2}, {}}", ast.PrintConfig{InlineTableWidth: 20}, "x = {1, {a = 2}, {}};\n"},
+		{"x = {1, 2, 3}", ast.PrintConfig{InlineTableWidth: 8}, "x = {\n\t1,\n\t2,\n\t3\n};\n"},
+		{"x = {1, 2}", ast.PrintConfig{TrailingCommas: true}, "x = {\n\t1,\n\t2,\n};\n"},
+		{"a()\n\n-- c\nb()\nc()", ast.PrintConfig{KeepBlankLines: true}, "a();\n\n-- c\nb();\nc();\n"},
+		{"x = 0x10 + 1e2", ast.PrintConfig{NormalizeNumbers: true}, "x = 16 + 100.0;\n"},
+		{`t["goto"] = {["goto"] = 1, ["to"] = 2}`, ast.PrintConfig{InlineTableWidth: 40}, "t[\"goto\"] = {[\"goto\"] = 1, to = 2};\n"},
+		{"x = ((a + b)) * (c).d return (f()), ((...))", ast.PrintConfig{}, "x = (a + b) * c.d;\nreturn (f()), (...);\n"},
+		{"x = ((a + b)) * (c).d", ast.PrintConfig{KeepParens: true}, "x = ((a + b)) * (c).d;\n"},
+		{`x = 'a', [==[b]]]==], "c", [[` + "\n\nd]]", ast.PrintConfig{KeepQuotes: true}, "x = 'a', [==[b]]]==], \"c\", [[\n\nd]];\n"},
+		{"x = [[a]=]], [[b\r\n]]", ast.PrintConfig{KeepQuotes: true}, "x = [[a]=]], [[b\n]];\n"},
+		{"t[ [[x y]] ] = {[ [=[a]=] .. b] = 1}", ast.PrintConfig{KeepQuotes: true}, "t[ [[x y]] ] = {\n\t[ [=[a]=] .. b ] = 1\n};\n"},
+	}
+
+	for _, test := range tests {
+		chunk, err := parse.Parse(strings.NewReader(test.src), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := ast.Fprint(&buf, chunk, &test.config); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != test.expected {
+			t.Errorf("%q:\nGot:\n%sExpected:\n%s", test.src, got, test.expected)
+		}
+	}
+}
+
+func TestLongBracketKeys(t *testing.T) {
+	src := "t[ [[x y]] ] = {[ [[a b]] ] = t[ [=[c]=] .. d ][ [[e f]] ]}\nprint(t [ [[f g]] ])"
+	chunk, err := parse.Parse(strings.NewReader(src), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	configs := []ast.PrintConfig{
+		{KeepQuotes: true},
+		{KeepQuotes: true, Compact: true},
+		{KeepQuotes: true, MaxWidth: 20},
+		{KeepQuotes: true, InlineTableWidth: 80},
+	}
+	for _, config := range configs {
+		got, err := ast.Format(chunk, &config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reparsed, err := parse.Parse(strings.NewReader(got), "")
+		if err != nil {
+			t.Fatalf("%+v: %v\n%s", config, err, got)
+		}
+		if !ast.Equal(chunk, reparsed, &ast.EqualOptions{IgnorePositions: true}) {
+			t.Errorf("%+v: expected the same tree back from\n%s", config, got)
+		}
+	}
+}
+
+func TestFormatDefault(t *testing.T) {
+	chunk, err := parse.Parse(strings.NewReader(test), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ast.Format(chunk, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := chunk.String(); got != expected {
+		t.Fatalf("\nGot:\n%sExpected:\n%s", got, expected)
+	}
+
+	got, err = ast.Format(chunk[0], &ast.PrintConfig{Semicolons: ast.SemicolonsNever})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.HasSuffix(strings.TrimSpace(got), ";") {
+		t.Errorf("Expected no semicolon, got %q", got)
+	}
+}
+
+func TestMaxWidth(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{"f(a, b)", "f(a, b);\n"},
+		{"local t = {1, 2, x = {3}}", "local t = {1, 2, x = {3}};\n"},
+		{
+			"local x = callSomething(argumentOne, argumentTwo, argumentThree)",
+			"local x = callSomething(\n  argumentOne,\n  argumentTwo,\n  argumentThree\n);\n",
+		},
+		{
+			"if someCondition and otherCondition or thirdCondition then end",
+			"if someCondition and otherCondition\n  or thirdCondition then\nend;\n",
+		},
+		{
+			"s = 'aaaaaaaaaaaaaaaa' .. bbbbbbbbbbbbbbbbbbbb .. 'cccccccccccc'",
+			"s = \"aaaaaaaaaaaaaaaa\"\n  .. bbbbbbbbbbbbbbbbbbbb\n  .. \"cccccccccccc\";\n",
+		},
+		{
+			"local t = {alpha = 1, beta = {1, 2}, gamma = 'some string'}",
+			"local t = {\n  alpha = 1,\n  beta = {1, 2},\n  gamma = \"some string\",\n};\n",
+		},
+		{
+			"local function longFunctionName(parameterOne, parameterTwo) end",
+			"local function longFunctionName(\n  parameterOne,\n  parameterTwo\n)\nend;\n",
+		},
+		{
+			"foo(bar, function(a, b) return a + b end)",
+			"foo(bar, function(a, b)\n  return a + b;\nend);\n",
+		},
+		{
+			"foo(function() return 1 end, argumentNumberOne, argumentNumberTwo)",
+			"foo(\n  function()\n    return 1;\n  end,\n  argumentNumberOne,\n  argumentNumberTwo\n);\n",
+		},
+	}
+
+	config := &ast.PrintConfig{Indent: 2, MaxWidth: 40, TrailingCommas: true}
+	for _, test := range tests {
+		chunk, err := parse.Parse(strings.NewReader(test.src), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ast.Format(chunk, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.expected {
+			t.Errorf("%q:\nGot:\n%sExpected:\n%s", test.src, got, test.expected)
+		}
+	}
+
+	// Wrapping only changes the layout.
+	chunk, err := parse.Parse(strings.NewReader(test), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrapped, err := ast.Format(chunk, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reparsed, err := parse.Parse(strings.NewReader(wrapped), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, expected := reparsed.String(), chunk.String(); got != expected {
+		t.Fatalf("\nGot:\n%sExpected:\n%s", got, expected)
+	}
+}
+
+func TestMaxWidthMatches(t *testing.T) {
+	src := "x = (\"\").len(s) .. ('a'):rep(2) .. t.x .. t['y z'] .. t['end'] .. t[ [[a b]] ] .. (-a) ^ - -b\n" +
+		"y = (f or g)(o:m'a', f'b', (...), (f()), (a).b)\n"
+	chunk, err := parse.Parse(strings.NewReader(src), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Expressions that fit are printed the same with or without MaxWidth.
+	for _, config := range []ast.PrintConfig{{}, {OmitCallParens: true}, {KeepParens: true}, {KeepQuotes: true}} {
+		flat, err := ast.Format(chunk, &config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		config.MaxWidth = 1000
+		wide, err := ast.Format(chunk, &config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if wide != flat {
+			t.Errorf("%+v:\nGot:\n%sExpected:\n%s", config, wide, flat)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/notnoobmaster/luautil/ast"
+	"github.com/notnoobmaster/luautil/parse"
+)
+
+func TestFormatRange(t *testing.T) {
+	src := `local  a   =   {1,2}
+function f(x)
+    if x then
+        print( x ,a )   -- show
+        x=x+1
+    elseif   x == nil then
+        return   1
+    end
+    local t = {1,
+      2}
+end
+call( function()   return  1   end )
+if y then z=1 end
+a = 1; (g)()
+`
+	tests := []struct {
+		start, end int
+		expected   string
+	}{
+		// Statements in a block keep the indentation of the first one.
+		{4, 4, `local  a   =   {1,2}
+function f(x)
+    if x then
+        print(x, a); -- show
+        x=x+1
+    elseif   x == nil then
+        return   1
+    end
+    local t = {1,
+      2}
+end
+call( function()   return  1   end )
+if y then z=1 end
+a = 1; (g)()
+`},
+		{4, 5, `local  a   =   {1,2}
+function f(x)
+    if x then
+        print(x, a); -- show
+        x = x + 1;
+    elseif   x == nil then
+        return   1
+    end
+    local t = {1,
+      2}
+end
+call( function()   return  1   end )
+if y then z=1 end
+a = 1; (g)()
+`},
+		// The range spans a multi-line statement partly.
+		{10, 10, `local  a   =   {1,2}
+function f(x)
+    if x then
+        print( x ,a )   -- show
+        x=x+1
+    elseif   x == nil then
+        return   1
+    end
+    local t = {
+    	1,
+    	2
+    };
+end
+call( function()   return  1   end )
+if y then z=1 end
+a = 1; (g)()
+`},
+		// An elseif branch.
+		{7, 7, `local  a   =   {1,2}
+function f(x)
+    if x then
+        print( x ,a )   -- show
+        x=x+1
+    elseif   x == nil then
+        return 1;
+    end
+    local t = {1,
+      2}
+end
+call( function()   return  1   end )
+if y then z=1 end
+a = 1; (g)()
+`},
+		// The header of the elseif reformats the whole if.
+		{6, 6, `local  a   =   {1,2}
+function f(x)
+    if x then
+    	print(x, a); -- show
+    	x = x + 1;
+    elseif x == nil then
+    	return 1;
+    end;
+    local t = {1,
+      2}
+end
+call( function()   return  1   end )
+if y then z=1 end
+a = 1; (g)()
+`},
+		// Statements sharing their line with other code are formatted along
+		// with it.
+		{13, 13, `local  a   =   {1,2}
+function f(x)
+    if x then
+        print( x ,a )   -- show
+        x=x+1
+    elseif   x == nil then
+        return   1
+    end
+    local t = {1,
+      2}
+end
+call( function()   return  1   end )
+if y then
+	z = 1;
+end;
+a = 1; (g)()
+`},
+		{12, 12, `local  a   =   {1,2}
+function f(x)
+    if x then
+        print( x ,a )   -- show
+        x=x+1
+    elseif   x == nil then
+        return   1
+    end
+    local t = {1,
+      2}
+end
+call(function()
+	return 1;
+end);
+if y then z=1 end
+a = 1; (g)()
+`},
+		{1, 1, `local a = {
+	1,
+	2
+};
+function f(x)
+    if x then
+        print( x ,a )   -- show
+        x=x+1
+    elseif   x == nil then
+        return   1
+    end
+    local t = {1,
+      2}
+end
+call( function()   return  1   end )
+if y then z=1 end
+a = 1; (g)()
+`},
+	}
+
+	for _, test := range tests {
+		got, err := parse.FormatRange([]byte(src), test.start, test.end, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != test.expected {
+			t.Errorf("Lines %d-%d:\nGot:\n%sExpected:\n%s", test.start, test.end, got, test.expected)
+		}
+	}
+
+	// The semicolon needed before a statement outside of the range is kept.
+	config := &ast.PrintConfig{Semicolons: ast.SemicolonsRequired}
+	got, err := parse.FormatRange([]byte("a = 1;\n(f or g)()\n"), 1, 1, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "a = 1;\n(f or g)()\n"; string(got) != expected {
+		t.Errorf("\nGot:\n%sExpected:\n%s", got, expected)
+	}
+
+	// Lines without statements are left alone.
+	got, err = parse.FormatRange([]byte("a  = 1\n\n\nb  = 2\n"), 2, 3, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "a  = 1\n\n\nb  = 2\n"; string(got) != expected {
+		t.Errorf("\nGot:\n%sExpected:\n%s", got, expected)
+	}
+}
+
+func TestFormatRangeNewlines(t *testing.T) {
+	tests := []struct {
+		src        string
+		start, end int
+		expected   string
+	}{
+		{"x=1\ry=2\rz=3\r", 2, 2, "x=1\ry = 2;\rz=3\r"},
+		{"x=1\r\nif y then\r\n  z=3 end\r\nw=4\r\n", 2, 3, "x=1\r\nif y then\r\n\tz = 3;\r\nend;\r\nw=4\r\n"},
+		{"x=1\r\n--[[ a\r\nb ]] y=2\r\nz=3", 2, 3, "x=1\r\n--[[ a\r\nb ]]\r\ny = 2;\r\nz=3"},
+		{"x=1\n\ry=2\n\rz=3", 2, 2, "x=1\n\ry = 2;\n\rz=3"},
+	}
+
+	for _, test := range tests {
+		got, err := parse.FormatRange([]byte(test.src), test.start, test.end, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != test.expected {
+			t.Errorf("%q lines %d-%d:\nGot:      %q\nExpected: %q", test.src, test.start, test.end, got, test.expected)
+		}
+	}
+}
+
+func TestFormatRangeDialect(t *testing.T) {
+	src := "local x: number=1\nlocal y: string=''\n"
+	got, err := parse.FormatRangeWithOptions([]byte(src), 1, 1, parse.Options{Dialect: parse.Luau}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "local x: number = 1;\nlocal y: string=''\n"; string(got) != expected {
+		t.Errorf("\nGot:\n%sExpected:\n%s", got, expected)
+	}
+}
@@ -0,0 +1,65 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/notnoobmaster/luautil/ast"
+	"github.com/notnoobmaster/luautil/parse"
+)
+
+func TestParseAll(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected string
+		errors   int
+	}{
+		{"x = = 1\ny = 2\nlocal z = 3\n", "x = --[[bad expression]];\ny = 2;\nlocal z = 3;\n", 1},
+		{"local a = 1 + * 2\nb()\n", "local a = --[[bad expression]];\nb();\n", 1},
+		{"function f()\n\tx = 1\n", "function f()\n\tx = 1;\nend;\n", 1},
+		{"function f()\n\tx = \nend\nf()\n", "function f()\n\tx = --[[bad expression]];\nend;\nf();\n", 1},
+		{"if a b then\nc()\nend\nd()\n", "--[[bad statement]];\nc();\nd();\n", 2},
+		{"function f()\n\treturn 1 x = 2\nend\n", "function f()\n\treturn 1;\n\tx = 2;\nend;\n", 1},
+		{"end\nx = 1\n", "x = 1;\n", 1},
+		{"x\ny = 1\n", "--[[bad statement]];\ny = 1;\n", 1},
+		{"x = @ 1\n", "x = 1;\n", 1},
+		{"x = 1 )\n", "x = 1;\n--[[bad statement]];\n", 1},
+		{"repeat until\n", "repeat\nuntil --[[bad expression]];\n", 1},
+		{"local t: {x: number = 1\n", "--[[bad statement]];\n", 1},
+		{"x = 1\n", "x = 1;\n", 0},
+	}
+
+	for _, test := range tests {
+		chunk, err := parse.ParseAll(strings.NewReader(test.src), "")
+		errors, _ := err.(parse.ErrorList)
+		if len(errors) != test.errors || (err == nil) != (test.errors == 0) {
+			t.Fatalf("Expected %d errors for %q, got %v", test.errors, test.src, err)
+		}
+		if got := chunk.String(); got != test.expected {
+			t.Fatalf("\nGot:\n%sExpected:\n%s", got, test.expected)
+		}
+	}
+}
+
+func TestParseAllSpans(t *testing.T) {
+	src := "x = 1 + * 2 3\ny = 2\n"
+	chunk, err := parse.ParseAll(strings.NewReader(src), "")
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+
+	bad := chunk[0].(*ast.AssignStmt).Rhs[0].(*ast.BadExpr)
+	if text := src[bad.Pos().Offset:bad.End().Offset]; text != "1 + * 2 3" {
+		t.Fatalf("Expected BadExpr to span %q, got %q", "1 + * 2 3", text)
+	}
+	if end := chunk[0].End(); end != bad.End() {
+		t.Fatalf("Expected statement to end with BadExpr at %v, got %v", bad.End(), end)
+	}
+}
+
+func TestParseStopsAtFirstError(t *testing.T) {
+	_, err := parse.Parse(strings.NewReader("x = = 1\ny = = 2\n"), "")
+	if _, ok := err.(*parse.Error); !ok {
+		t.Fatalf("Expected a single *parse.Error, got %T", err)
+	}
+}
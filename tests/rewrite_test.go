@@ -0,0 +1,105 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/notnoobmaster/luautil/ast"
+	"github.com/notnoobmaster/luautil/parse"
+)
+
+func apply(t *testing.T, src string, pre, post ast.ApplyFunc) string {
+	return ast.Apply(mustParse(t, src), pre, post).(ast.Chunk).String()
+}
+
+func TestApplyReplace(t *testing.T) {
+	src := "_ = f(_);\n"
+	expected := "_ = \"f\";\n"
+
+	got := apply(t, src, func(c *ast.Cursor) bool {
+		if call, ok := c.Node().(*ast.FuncCallExpr); ok {
+			c.Replace(&ast.StringExpr{Value: call.Func.String()})
+		}
+		return true
+	}, nil)
+
+	if got != expected {
+		t.Fatalf("\nGot:\n%sExpected:\n%s", got, expected)
+	}
+}
+
+func TestApplyInsert(t *testing.T) {
+	src := "do\n\t_();\nend;\n"
+	expected := "do\n\tbefore();\n\t_();\n\tafter();\nend;\n"
+
+	call := func(name string) ast.Stmt {
+		return &ast.FuncCallStmt{Expr: &ast.FuncCallExpr{Func: &ast.IdentExpr{Value: name}}}
+	}
+
+	visited := 0
+	got := apply(t, src, func(c *ast.Cursor) bool {
+		if _, ok := c.Node().(*ast.FuncCallStmt); ok {
+			visited++
+			if _, ok := c.Parent().(*ast.DoBlockStmt); !ok || c.Name() != "Chunk" {
+				t.Fatalf("Unexpected parent %T.%s", c.Parent(), c.Name())
+			}
+			c.InsertBefore(call("before"))
+			c.InsertAfter(call("after"))
+		}
+		return true
+	}, nil)
+
+	if got != expected {
+		t.Fatalf("\nGot:\n%sExpected:\n%s", got, expected)
+	}
+	if visited != 1 {
+		t.Fatalf("Inserted nodes must not be walked, visited %d calls", visited)
+	}
+}
+
+func TestApplyDelete(t *testing.T) {
+	src := "_ = 1;\n_();\n_ = 2;\n_(1, _(), 2);\n"
+	expected := "_ = 1;\n_ = 2;\n_(1, 2);\n"
+
+	got := apply(t, src, func(c *ast.Cursor) bool {
+		if _, ok := c.Node().(*ast.FuncCallExpr); ok && c.Name() == "Args" {
+			c.Delete()
+		}
+		if s, ok := c.Node().(*ast.FuncCallStmt); ok && len(s.Expr.(*ast.FuncCallExpr).Args) == 0 {
+			if _, ok := c.Parent().(ast.Chunk); !ok || c.Index() != 1 {
+				t.Fatalf("Unexpected parent %T at index %d", c.Parent(), c.Index())
+			}
+			c.Delete()
+		}
+		return true
+	}, nil)
+
+	if got != expected {
+		t.Fatalf("\nGot:\n%sExpected:\n%s", got, expected)
+	}
+}
+
+func TestApplyAbort(t *testing.T) {
+	src := "_ = 1;\n_ = 2;\n_ = 3;\n"
+
+	var seen []string
+	ast.Apply(mustParse(t, src), nil, func(c *ast.Cursor) bool {
+		if n, ok := c.Node().(*ast.NumberExpr); ok {
+			seen = append(seen, n.String())
+			return n.Value < 2
+		}
+		return true
+	})
+
+	if strings.Join(seen, ",") != "1,2" {
+		t.Fatalf("Expected traversal to stop after 2, got %v", seen)
+	}
+}
+
+func mustParse(t *testing.T, src string) ast.Chunk {
+	chunk, err := parse.Parse(strings.NewReader(src), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return chunk
+}
@@ -0,0 +1,123 @@
+package tests
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/notnoobmaster/luautil/ast"
+	"github.com/notnoobmaster/luautil/parse"
+)
+
+func TestSourceMap(t *testing.T) {
+	chunk, err := parse.Parse(strings.NewReader("x = 1"), "input.lua")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sourceMap := &ast.SourceMap{File: "output.lua"}
+	if _, err := ast.Format(chunk, &ast.PrintConfig{SourceMap: sourceMap}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(sourceMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"version":3,"file":"output.lua","sources":["input.lua"],"names":[],"mappings":"AAAA,IAAI"}`
+	if string(data) != expected {
+		t.Fatalf("\nGot:      %s\nExpected: %s", data, expected)
+	}
+
+	src := "-- header\n\nlocal x = 1\n\n\nif x then\n  error(\n    'boom')\nend"
+	chunk, err = parse.Parse(strings.NewReader(src), "input.lua")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sourceMap = &ast.SourceMap{File: "output.lua"}
+	got, err := ast.Format(chunk, &ast.PrintConfig{SourceMap: sourceMap})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != chunk.String() {
+		t.Fatalf("\nGot:\n%sExpected:\n%s", got, chunk.String())
+	}
+	// error("boom") is printed on line 4.
+	if pos, ok := sourceMap.Lookup(4, 8); !ok || pos.Line != 8 || pos.Column != 5 {
+		t.Errorf("Expected 4:8 to map to 8:5, got %v", pos)
+	}
+	msg := "output.lua:4: boom\nstack traceback:\n\toutput.lua:4: in main chunk\n\tother.lua:4: in ?"
+	expected = "input.lua:7: boom\nstack traceback:\n\tinput.lua:7: in main chunk\n\tother.lua:4: in ?"
+	if got := sourceMap.TranslateError(msg); got != expected {
+		t.Errorf("\nGot:\n%s\nExpected:\n%s", got, expected)
+	}
+}
+
+func TestSourceMapOutput(t *testing.T) {
+	chunk, err := parse.Parse(strings.NewReader(test), "test.lua")
+	if err != nil {
+		t.Fatal(err)
+	}
+	configs := []ast.PrintConfig{{}, {Compact: true}, {MaxWidth: 40, Indent: 2}}
+	for _, config := range configs {
+		expected, err := ast.Format(chunk, &config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		config.SourceMap = &ast.SourceMap{}
+		got, err := ast.Format(chunk, &config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != expected {
+			t.Fatalf("Expected the source map not to change the output\nGot:\n%s\nExpected:\n%s", got, expected)
+		}
+		mappings := config.SourceMap.Mappings
+		if len(mappings) == 0 {
+			t.Fatal("Expected mappings")
+		}
+		lines := strings.Split(got, "\n")
+		for i, m := range mappings {
+			if i > 0 && (m.Line < mappings[i-1].Line || m.Line == mappings[i-1].Line && m.Column <= mappings[i-1].Column) {
+				t.Fatalf("Mappings are not sorted: %v after %v", m, mappings[i-1])
+			}
+			if m.Line > len(lines) || m.Column > len(lines[m.Line-1])+1 {
+				t.Fatalf("Mapping %v is outside of the output", m)
+			}
+		}
+	}
+}
+
+func TestSourceMapNUL(t *testing.T) {
+	srcs := []string{
+		"local x = 1 -- a\x00b\nprint(x)\n",
+		"local x = 1 -- a\x000\x00b\nprint(x)\n",
+		"local s = [[a\x00b]]\nprint(s)\n",
+		"f(function()\n\tg() -- a\x00b\nend, [[\x001\x00]])\n",
+	}
+
+	for _, src := range srcs {
+		chunk, err := parse.Parse(strings.NewReader(src), "input.lua")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, config := range []ast.PrintConfig{
+			{KeepQuotes: true},
+			{KeepQuotes: true, MaxWidth: 20},
+		} {
+			expected, err := ast.Format(chunk, &config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			config.SourceMap = &ast.SourceMap{}
+			got, err := ast.Format(chunk, &config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != expected {
+				t.Errorf("%q:\nGot:\n%q\nExpected:\n%q", src, got, expected)
+			}
+			if pos, ok := config.SourceMap.LookupLine(2); !ok || pos.Line != 2 {
+				t.Errorf("%q: expected line 2 to map to line 2, got %v", src, pos)
+			}
+		}
+	}
+}
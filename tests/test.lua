@@ -0,0 +1,41 @@
+local Stack = {};
+Stack.__index = Stack;
+function Stack.new(...)
+	local self = setmetatable({
+		items = {
+			...
+		},
+		size = select("#", ...)
+	}, Stack);
+	return self;
+end;
+function Stack:push(value)
+	self.size += 1;
+	self.items[self.size] = value;
+end;
+function Stack:pop()
+	if self.size == 0 then
+		return nil;
+	end;
+	local value = self.items[self.size];
+	self.items[self.size] = nil;
+	self.size = self.size - 1;
+	return value;
+end;
+local function sum(t)
+	local total = 0;
+	for _, v in ipairs(t) do
+		if type(v) ~= "number" then
+			continue;
+		end;
+		total = total + v;
+	end;
+	return total;
+end;
+for i = 1, 10, 2 do
+	print(i, i ^ 2 % 3, not (i > 5 and i < 8) or (-i));
+end;
+return {
+	Stack = Stack,
+	sum = sum
+};
@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/notnoobmaster/luautil/ast"
+	"github.com/notnoobmaster/luautil/parse"
+)
+
+func TestTokenize(t *testing.T) {
+	tokens, err := parse.Tokenize(strings.NewReader("local x = 1 -- one\nprint(x .. 'a')"), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []struct {
+		kind parse.TokenKind
+		str  string
+	}{
+		{parse.KindKeyword, "local"}, {parse.KindIdent, "x"}, {parse.KindOperator, "="},
+		{parse.KindNumber, "1"}, {parse.KindComment, "-- one"}, {parse.KindIdent, "print"},
+		{parse.KindPunctuation, "("}, {parse.KindIdent, "x"}, {parse.KindOperator, ".."},
+		{parse.KindString, "a"}, {parse.KindPunctuation, ")"},
+	}
+	if len(tokens) != len(expected) {
+		t.Fatalf("Expected %d tokens, got %d: %v", len(expected), len(tokens), tokens)
+	}
+	for i, tok := range tokens {
+		if kind := parse.KindOf(tok.Type); kind != expected[i].kind || tok.Str != expected[i].str {
+			t.Errorf("Token %d: expected %v %q, got %v %q", i, expected[i].kind, expected[i].str, kind, tok.Str)
+		}
+	}
+	comment := tokens[4]
+	if comment.Pos != (ast.Position{Source: "test", Line: 1, Column: 13, Offset: 12}) || comment.End.Column != 19 {
+		t.Errorf("Unexpected position of the comment: %v to %v", comment.Pos, comment.End)
+	}
+	print := tokens[5]
+	if print.Pos.Line != 2 || print.Pos.Column != 1 || print.Pos.Offset != 19 {
+		t.Errorf("Unexpected position of print: %v", print.Pos)
+	}
+}
+
+func TestTokenizerWhitespace(t *testing.T) {
+	src := "--[==[ long\ncomment ]==]  x = `a {b} c`\r\n\t-- last"
+	tokenizer := parse.NewTokenizer(strings.NewReader(src), "", parse.TokenizerOptions{Comments: true, Whitespace: true})
+	var text strings.Builder
+	var prev ast.Token
+	for {
+		tok, err := tokenizer.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if prev.Type != 0 && tok.Pos != prev.End {
+			t.Fatalf("Expected %v to start at the end of %v", tok, prev)
+		}
+		if tok.Type == parse.EOF {
+			if tok.Pos.Offset != len(src) || tok.Pos.Line != 3 {
+				t.Fatalf("Unexpected position of the end: %v", tok.Pos)
+			}
+			break
+		}
+		switch tok.Type {
+		case parse.TWhitespace, parse.TComment:
+			text.WriteString(tok.Str)
+		default:
+			text.WriteString(src[tok.Pos.Offset:tok.End.Offset])
+		}
+		prev = tok
+	}
+	if text.String() != src {
+		t.Fatalf("\nGot:\n%q\nExpected:\n%q", text.String(), src)
+	}
+	if tok, _ := tokenizer.Next(); tok.Type != parse.EOF {
+		t.Fatalf("Expected EOF again, got %v", tok)
+	}
+}
+
+func TestTokenizerDialect(t *testing.T) {
+	for _, test := range []struct {
+		dialect parse.Dialect
+		kind    parse.TokenKind
+	}{{parse.Lua51, parse.KindIdent}, {parse.Lua52, parse.KindKeyword}, {parse.Luau, parse.KindIdent}} {
+		tok, err := parse.NewTokenizer(strings.NewReader("goto"), "", parse.TokenizerOptions{Dialect: test.dialect}).Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if kind := parse.KindOf(tok.Type); kind != test.kind {
+			t.Errorf("Expected goto to be a %v in %v, got %v", test.kind, test.dialect, kind)
+		}
+	}
+
+	tokens, err := parse.Tokenize(strings.NewReader("x = 'unterminated"), "")
+	if _, ok := err.(*parse.Error); !ok || len(tokens) != 2 {
+		t.Fatalf("Expected a *parse.Error after 2 tokens, got %v after %v", err, tokens)
+	}
+}
+
+func TestTokenizerStreams(t *testing.T) {
+	broken := errors.New("broken")
+	reader := io.MultiReader(strings.NewReader("local x = 1 -- one\n"), iotest.ErrReader(broken))
+	tokens, err := parse.Tokenize(iotest.OneByteReader(reader), "")
+	if err != broken {
+		t.Fatalf("Expected the read error, got %v", err)
+	}
+	var got []string
+	for _, tok := range tokens {
+		got = append(got, tok.Str)
+	}
+	if strings.Join(got, " ") != "local x = 1" {
+		t.Fatalf("Expected the tokens read before the error, got %q", got)
+	}
+}
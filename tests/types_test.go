@@ -0,0 +1,144 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/notnoobmaster/luautil/ast"
+	"github.com/notnoobmaster/luautil/parse"
+)
+
+func TestTypesRoundTrip(t *testing.T) {
+	tests := []string{
+		"local x: number = 1;\n",
+		"local a: string, b, c: boolean? = f();\n",
+		"local x = y :: number;\n",
+		"local x = (y :: any) :: string;\n",
+		"type Point = {x: number, y: number};\n",
+		"export type List<T> = {T};\n",
+		"type Map<K, V> = {[K]: V};\n",
+		"type Pair<T...> = (T...) -> (T...);\n",
+		"type F = (number, string) -> boolean;\n",
+		"type F = (a: number, ...string) -> ();\n",
+		"type F = <T>(T) -> (T, T);\n",
+		"type F = () -> (...number);\n",
+		"type T<A = number, B... = ...string> = (A) -> (B...);\n",
+		"type T<A, B = {A}, C... = B...> = (B) -> (C...);\n",
+		"type T<A = (number) -> string> = A;\n",
+		"type U = string | number | nil;\n",
+		"type I = A & B;\n",
+		"type O = (A | B)?;\n",
+		"type S = \"on\" | \"off\" | true;\n",
+		"type T = typeof(x);\n",
+		"type N = Foo<Bar<T>>;\n",
+		"type M = mod.Type<number>;\n",
+		"type G = (() -> ()) | string;\n",
+		"function f<T>(a: string, ...: number): boolean\nend;\n",
+		"local function f(a: T): (number, string)\nend;\n",
+		"local function f(): (...number)\nend;\n",
+		"x = function(a, b: number): () -> ()\nend;\n",
+		"for i: number = 1, 10 do\nend;\n",
+		"for k: string, v in pairs(t) do\nend;\n",
+		"for k, v: {number} in pairs(t) do\nend;\n",
+	}
+
+	for _, src := range tests {
+		chunk, err := parse.ParseWithOptions(strings.NewReader(src), "", parse.Options{Dialect: parse.Luau})
+		if err != nil {
+			t.Errorf("%q: %v", src, err)
+			continue
+		}
+		if got := chunk.String(); got != src {
+			t.Errorf("Expected %q, got %q", src, got)
+		}
+	}
+}
+
+func TestTypesParse(t *testing.T) {
+	chunk, err := parse.ParseWithOptions(strings.NewReader("local a, b: number? = 1"), "", parse.Options{Dialect: parse.Luau})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmt := chunk[0].(*ast.LocalAssignStmt)
+	if len(stmt.Types) != 2 || stmt.Types[0] != nil {
+		t.Fatalf("Expected a type for b only, got %v", stmt.Types)
+	}
+	opt, ok := stmt.Types[1].(*ast.OptionalType)
+	if !ok {
+		t.Fatalf("Expected *ast.OptionalType, got %T", stmt.Types[1])
+	}
+	if ref, ok := opt.Type.(*ast.TypeReference); !ok || ref.Name != "number" {
+		t.Errorf("Expected number, got %v", opt.Type)
+	}
+
+	normalized := []struct{ src, expected string }{
+		{"local x = y :: any :: number", "local x = (y :: any) :: number;\n"},
+		{"function f(): ...number end", "function f(): (...number)\nend;\n"},
+		{"type F = () -> ...number", "type F = () -> (...number);\n"},
+		{"type F = (number) -> ...T?", "type F = (number) -> (...T?);\n"},
+	}
+	for _, test := range normalized {
+		chunk, err := parse.ParseWithOptions(strings.NewReader(test.src), "", parse.Options{Dialect: parse.Luau})
+		if err != nil {
+			t.Errorf("%q: %v", test.src, err)
+		} else if got := chunk.String(); got != test.expected {
+			t.Errorf("Expected %q, got %q", test.expected, got)
+		}
+	}
+
+	chunk, err = parse.ParseWithOptions(strings.NewReader("type T<A, B = number> = A"), "", parse.Options{Dialect: parse.Luau})
+	if err != nil {
+		t.Fatal(err)
+	}
+	alias := chunk[0].(*ast.TypeAliasStmt)
+	if len(alias.Defaults) != 2 || alias.Defaults[0] != nil || alias.Defaults[1] == nil {
+		t.Errorf("Expected a default for B only, got %v", alias.Defaults)
+	}
+
+	// type is only a keyword in front of a name.
+	for _, src := range []string{"type = 1", "type(x)", "local type: string = type(x)", "local x: A<B<C>>= y"} {
+		if _, err := parse.ParseWithOptions(strings.NewReader(src), "", parse.Options{Dialect: parse.Luau}); err != nil {
+			t.Errorf("%q: %v", src, err)
+		}
+	}
+}
+
+func TestTypesErrors(t *testing.T) {
+	tests := []struct {
+		src     string
+		message string
+	}{
+		{"type T<A = number, B> = A", "default type expected after B"},
+		{"function f<T = number>() end", "generic defaults are only allowed in type aliases"},
+		{"type F = <T = number>(T) -> T", "generic defaults are only allowed in type aliases"},
+		{"type T<A... = (string, number)> = A", "type pack lists are not supported as generic defaults"},
+		{"x = y :: ", "type expected"},
+	}
+	for _, test := range tests {
+		_, err := parse.ParseWithOptions(strings.NewReader(test.src), "", parse.Options{Dialect: parse.Luau})
+		if e, ok := err.(*parse.Error); !ok || e.Message != test.message {
+			t.Errorf("%q: expected %q, got %v", test.src, test.message, err)
+		}
+	}
+}
+
+func TestTypesDialect(t *testing.T) {
+	tests := []struct {
+		src     string
+		message string
+	}{
+		{"local x: number = 1", "type annotations are not supported in Lua 5.3"},
+		{"function f(a: number) end", "type annotations are not supported in Lua 5.3"},
+		{"function f(): number end", "type annotations are not supported in Lua 5.3"},
+		{"function f<T>() end", "generics are not supported in Lua 5.3"},
+	}
+	for _, test := range tests {
+		_, err := parse.ParseWithOptions(strings.NewReader(test.src), "", parse.Options{Dialect: parse.Lua53})
+		if err == nil || !strings.Contains(err.Error(), test.message) {
+			t.Errorf("%q: expected %q, got %v", test.src, test.message, err)
+		}
+	}
+	if _, err := parse.ParseWithOptions(strings.NewReader("type Foo = number"), "", parse.Options{Dialect: parse.Lua53}); err == nil {
+		t.Errorf("Expected Lua 5.3 to reject a type alias")
+	}
+}
@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/notnoobmaster/luautil/ast"
+	"github.com/notnoobmaster/luautil/parse"
+)
+
+func TestInspect(t *testing.T) {
+	src := "function a.b:c(d, ...) local e = {f = 1, [g] = h, i} end; x += y(z)\n"
+	expected := []string{
+		"ast.Chunk",
+		"*ast.FunctionStmt",
+		"*ast.FuncName",
+		"*ast.AttrGetExpr",
+		"*ast.IdentExpr",
+		"*ast.StringExpr",
+		"*ast.FunctionExpr",
+		"*ast.ParList",
+		"ast.Chunk",
+		"*ast.LocalAssignStmt",
+		"*ast.TableExpr",
+		"*ast.Field",
+		"*ast.StringExpr",
+		"*ast.NumberExpr",
+		"*ast.Field",
+		"*ast.IdentExpr",
+		"*ast.IdentExpr",
+		"*ast.Field",
+		"*ast.IdentExpr",
+		"*ast.CompoundAssignStmt",
+		"*ast.IdentExpr",
+		"*ast.FuncCallExpr",
+		"*ast.IdentExpr",
+		"*ast.IdentExpr",
+	}
+
+	chunk, err := parse.Parse(strings.NewReader(src), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	ast.Inspect(chunk, func(n ast.Node) bool {
+		if n != nil {
+			got = append(got, fmt.Sprintf("%T", n))
+		}
+		return true
+	})
+
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("\nGot:\n%s\nExpected:\n%s", strings.Join(got, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestInspectPrune(t *testing.T) {
+	src := "local function f() g() end; h()\n"
+
+	chunk, err := parse.Parse(strings.NewReader(src), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var calls []string
+	ast.Inspect(chunk, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FunctionExpr:
+			return false
+		case *ast.FuncCallExpr:
+			calls = append(calls, n.Func.String())
+		}
+		return true
+	})
+
+	if len(calls) != 1 || calls[0] != "h" {
+		t.Fatalf("Expected only the top level call to be visited, got %v", calls)
+	}
+}
+
+type countVisitor map[string]int
+
+func (v countVisitor) Visit(n ast.Node) ast.Visitor {
+	if n == nil {
+		v["nil"]++
+		return nil
+	}
+	v[fmt.Sprintf("%T", n)]++
+	return v
+}
+
+func TestWalkBalanced(t *testing.T) {
+	src := "while a do if b then c() elseif d then repeat until e else return end end\n"
+
+	chunk, err := parse.Parse(strings.NewReader(src), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := countVisitor{}
+	ast.Walk(v, chunk)
+
+	nodes := 0
+	for k, n := range v {
+		if k != "nil" {
+			nodes += n
+		}
+	}
+	if nodes != v["nil"] {
+		t.Fatalf("Expected one Visit(nil) per visited node, got %d nodes and %d nils", nodes, v["nil"])
+	}
+	if v["*ast.IfStmt"] != 2 {
+		t.Fatalf("Expected 2 if statements, got %d", v["*ast.IfStmt"])
+	}
+}
+
+func TestWalkComment(t *testing.T) {
+	v := countVisitor{}
+	ast.Walk(v, &ast.Comment{Text: "-- c"})
+
+	if v["*ast.Comment"] != 1 || v["nil"] != 1 {
+		t.Fatalf("Expected the comment to be visited once, got %v", v)
+	}
+}